@@ -0,0 +1,59 @@
+// Command scheduler runs internal/media/scheduler's periodic sync loop
+// and exposes its progress over HTTP, for a dashboard to poll instead of
+// just waiting on a one-shot CLI run (see internal/main.go) or the queue
+// worker's "refresh:trakt" job.
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/skamensky/skam.dev/internal/media/scheduler"
+
+	// Blank-imported so each provider's init() registers itself with
+	// common.RegisterProvider before the first sync runs.
+	_ "github.com/skamensky/skam.dev/internal/media"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sched := scheduler.New()
+	if mins, err := strconv.Atoi(os.Getenv("SYNC_INTERVAL_MINUTES")); err == nil && mins > 0 {
+		sched.Interval = time.Duration(mins) * time.Minute
+	}
+
+	mux := http.NewServeMux()
+	sched.Routes(mux, "/status")
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	httpServer := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+
+	go func() {
+		log.Printf("scheduler status endpoint listening on :%s", port)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("scheduler status server exited: %v", err)
+		}
+	}()
+
+	log.Printf("scheduler starting, syncing every %s", sched.Interval)
+	if err := sched.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Fatalf("scheduler exited: %v", err)
+	}
+	log.Println("scheduler shut down")
+}
@@ -0,0 +1,61 @@
+// Command webhook runs the HTTP receiver for Plex/Jellyfin scrobble
+// webhooks (internal/media/webhook), forwarding play/pause/stop events to
+// Trakt as they happen instead of waiting for the next scheduled sync.
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/skamensky/skam.dev/internal/db"
+	"github.com/skamensky/skam.dev/internal/media/traktv"
+	"github.com/skamensky/skam.dev/internal/media/webhook"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	gdb, err := db.NewDB()
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+
+	trakt := &traktv.TraktItemList{}
+	if err := trakt.Init(gdb); err != nil {
+		log.Fatalf("failed to initialize trakt client: %v", err)
+	}
+
+	authToken := os.Getenv("WEBHOOK_AUTH_TOKEN")
+	if authToken == "" {
+		log.Fatal("WEBHOOK_AUTH_TOKEN environment variable is required")
+	}
+
+	srv := webhook.NewServer(gdb, trakt)
+	srv.DiscordWebhookURL = os.Getenv("DISCORD_WEBHOOK_URL")
+	srv.AuthToken = authToken
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	httpServer := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+
+	log.Printf("webhook server listening on :%s", port)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("webhook server exited: %v", err)
+	}
+	log.Println("webhook server shut down")
+}
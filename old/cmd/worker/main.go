@@ -0,0 +1,63 @@
+// Command worker dequeues and executes media refresh jobs independently of
+// the initializer binary (internal/main.go), so a single failing TMDB/Trakt
+// call retries on its own schedule instead of aborting the whole run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/skamensky/skam.dev/internal/db"
+	"github.com/skamensky/skam.dev/internal/media"
+	"github.com/skamensky/skam.dev/internal/media/common"
+	"github.com/skamensky/skam.dev/internal/media/queue"
+	"github.com/skamensky/skam.dev/internal/media/traktv"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	gdb, err := db.NewDB()
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+
+	q := queue.New(gdb)
+	w := queue.NewWorker(q)
+
+	w.Register("refresh:trakt", func(json.RawMessage) error {
+		return media.RefreshAllMediaItems(ctx)
+	})
+	w.Register("fetch:thumbnail", func(payload json.RawMessage) error {
+		var p common.ThumbnailJobPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		// TODO: providers don't yet persist a thumbnail URL alongside
+		// MediaItem, so there's nothing to download from here. Once one
+		// does, fetch it and write MediaItem.Thumbnail for p.MediaItemID.
+		log.Printf("skipping thumbnail fetch for %s: no thumbnail source persisted yet", p.MediaItemID)
+		return nil
+	})
+	w.Register("fetch:tmdb-details", func(payload json.RawMessage) error {
+		var p common.TmdbDetailsJobPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		fetcher, err := traktv.NewTmdbFetcher(gdb)
+		if err != nil {
+			return err
+		}
+		return fetcher.RetryTmdbDetails(p)
+	})
+
+	log.Println("worker started, polling for jobs")
+	if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Fatalf("worker exited: %v", err)
+	}
+	log.Println("worker shut down")
+}
@@ -0,0 +1,36 @@
+// Package cache provides a small keyed response cache for the HTTP calls
+// internal/media/traktv makes against TMDB and Trakt, so repeated refreshes
+// don't re-fetch data that hasn't changed.
+package cache
+
+import "time"
+
+// Entry is a cached HTTP response body plus the validators needed to do a
+// conditional GET against the origin once the TTL has elapsed.
+type Entry struct {
+	Value        []byte
+	ETag         string
+	LastModified string
+	ExpiresAt    time.Time
+}
+
+// Expired reports whether the entry is past its TTL and should be
+// revalidated with the origin before being reused as-is.
+func (e *Entry) Expired() bool {
+	return e == nil || time.Now().After(e.ExpiresAt)
+}
+
+// Store is a keyed cache of HTTP response entries. Keys are stable strings
+// like "tmdb.movie.<id>.<lang>" or "trakt.watched.<user>".
+type Store interface {
+	Get(key string) (*Entry, error)
+	Set(key string, entry *Entry) error
+	Purge(key string) error
+}
+
+// ErrNotFound is returned by Store.Get when the key has no cached entry.
+var ErrNotFound = errNotFound{}
+
+type errNotFound struct{}
+
+func (errNotFound) Error() string { return "cache: key not found" }
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	goerrors "errors"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// FSStore persists cache entries as JSON files under
+// $XDG_CACHE_HOME/skam.dev (os.UserCacheDir already honors XDG_CACHE_HOME
+// on Linux), one file per key.
+type FSStore struct {
+	dir string
+}
+
+func NewFSStore() (*FSStore, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve user cache dir")
+	}
+	dir := filepath.Join(base, "skam.dev")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create cache dir")
+	}
+	return &FSStore{dir: dir}, nil
+}
+
+func (s *FSStore) pathFor(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FSStore) Get(key string) (*Entry, error) {
+	data, err := os.ReadFile(s.pathFor(key))
+	if goerrors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cache file")
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal cache entry")
+	}
+	return &entry, nil
+}
+
+func (s *FSStore) Set(key string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cache entry")
+	}
+	if err := os.WriteFile(s.pathFor(key), data, 0o644); err != nil {
+		return errors.Wrap(err, "failed to write cache file")
+	}
+	return nil
+}
+
+func (s *FSStore) Purge(key string) error {
+	err := os.Remove(s.pathFor(key))
+	if err != nil && !goerrors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "failed to remove cache file")
+	}
+	return nil
+}
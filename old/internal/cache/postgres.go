@@ -0,0 +1,60 @@
+package cache
+
+import (
+	goerrors "errors"
+
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+	"gorm.io/gorm"
+)
+
+// PGStore persists cache entries in the db.CacheEntry table, reusing the
+// module's existing Postgres connection rather than standing up a separate
+// cache service.
+type PGStore struct {
+	db *gorm.DB
+}
+
+func NewPGStore(gdb *gorm.DB) *PGStore {
+	return &PGStore{db: gdb}
+}
+
+func (s *PGStore) Get(key string) (*Entry, error) {
+	var row db.CacheEntry
+	res := s.db.Where("key = ?", key).First(&row)
+	if goerrors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, "failed to read cache entry")
+	}
+	return &Entry{
+		Value:        row.Value,
+		ETag:         row.ETag,
+		LastModified: row.LastModified,
+		ExpiresAt:    row.ExpiresAt,
+	}, nil
+}
+
+func (s *PGStore) Set(key string, entry *Entry) error {
+	row := db.CacheEntry{
+		Key:          key,
+		Value:        entry.Value,
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+		ExpiresAt:    entry.ExpiresAt,
+	}
+	res := s.db.Save(&row)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to write cache entry")
+	}
+	return nil
+}
+
+func (s *PGStore) Purge(key string) error {
+	res := s.db.Delete(&db.CacheEntry{}, "key = ?", key)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to purge cache entry")
+	}
+	return nil
+}
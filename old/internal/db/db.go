@@ -5,7 +5,6 @@ import (
 	"github.com/pkg/errors"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
 	"os"
 	"strings"
 )
@@ -61,49 +60,21 @@ func InitializeData() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to migrate media item")
 	}
-	traktAPIKey := os.Getenv("TRAKT_API_KEY")
-	traktSecret := os.Getenv("TRAKT_SECRET")
-	traktEmail := os.Getenv("TRAKT_EMAIL")
-	traktPassword := os.Getenv("TRAKT_PASSWORD")
-	tmdbSecret := os.Getenv("TMDB_SECRET")
-
-	traktAuth := MediaAuth{
-		APIKey:      traktAPIKey,
-		APISecret:   traktSecret,
-		Username:    traktEmail,
-		Password:    traktPassword,
-		ServiceName: "trakt",
-	}
-	tmdbAuth := MediaAuth{
-		APISecret:   tmdbSecret,
-		ServiceName: "tmdb",
-	}
-
-	// servicename to auth map
-	localAuthMap := map[string]MediaAuth{
-		"trakt": traktAuth,
-		"tmdb":  tmdbAuth,
-	}
-	dbAuths := []MediaAuth{}
-	res := db.Find(&dbAuths)
-	if res.Error != nil {
-		return errors.Wrap(res.Error, "failed to find auths")
+	err = db.AutoMigrate(&Job{})
+	if err != nil {
+		return errors.Wrap(err, "failed to migrate job")
 	}
-
-	dbAuthMap := map[string]MediaAuth{}
-	for _, auth := range dbAuths {
-		dbAuthMap[auth.ServiceName] = auth
+	err = db.AutoMigrate(&CacheEntry{})
+	if err != nil {
+		return errors.Wrap(err, "failed to migrate cache entry")
 	}
-
-	for serviceName, auth := range localAuthMap {
-		if _, ok := dbAuthMap[serviceName]; !ok {
-			log.Printf("Auth for service %s is missing in DB. Creating it.", serviceName)
-			res := db.Create(&auth)
-			if res.Error != nil {
-				return errors.Wrap(res.Error, "failed to create auth")
-			}
-		}
+	err = db.AutoMigrate(&LocalFile{})
+	if err != nil {
+		return errors.Wrap(err, "failed to migrate local file")
 	}
 
+	// Seeding each service's MediaAuth row from env vars is now each
+	// provider's own responsibility; see common.RegisterProvider's
+	// AuthSeeder and common.EnsureAuthRows, called from RefreshLists.
 	return nil
 }
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// HousekeepReport summarizes what a Housekeep run found and changed. It's
+// meant to be logged or rendered by the caller, not acted on further.
+type HousekeepReport struct {
+	DuplicateMediaItemsMerged int
+	OrphanedLocalFilesRemoved int
+	InvalidMediaAuthRemoved   int
+	// MissingTmdbCacheEntries lists MediaItem IDs that carry a tmdb id but
+	// have no corresponding cached TMDB details, so enrichWithTmdb hasn't
+	// run successfully for them yet.
+	MissingTmdbCacheEntries []string
+}
+
+// mediaItemIds is the subset of a MediaItem's Data blob Housekeep cares
+// about: the imdb/tmdb identifiers TraktEntry serializes under "ids".
+// Providers that don't use this shape (bilibili, podcast) simply never
+// match and are left alone.
+type mediaItemIds struct {
+	Ids struct {
+		Imdb string `json:"imdb"`
+		Tmdb int    `json:"tmdb"`
+	} `json:"ids"`
+}
+
+// Housekeep scans MediaItem and MediaAuth for the kind of drift that
+// accumulates over repeated syncs: duplicate MediaItem rows left behind by
+// differing primary IDs across runs, LocalFile rows pointing at MediaItems
+// that no longer exist, and MediaAuth rows that were never configured. It
+// also reports MediaItems whose TMDB enrichment is missing from the
+// response cache. Safe to run repeatedly; it only merges/deletes rows it
+// can prove are redundant or orphaned.
+func Housekeep(ctx context.Context, gdb *gorm.DB) (*HousekeepReport, error) {
+	gdb = gdb.WithContext(ctx)
+	report := &HousekeepReport{}
+
+	if err := dedupeMediaItems(gdb, report); err != nil {
+		return nil, errors.Wrap(err, "failed to dedupe media items")
+	}
+	if err := pruneOrphanedLocalFiles(gdb, report); err != nil {
+		return nil, errors.Wrap(err, "failed to prune orphaned local files")
+	}
+	if err := pruneInvalidMediaAuth(gdb, report); err != nil {
+		return nil, errors.Wrap(err, "failed to prune invalid media auth")
+	}
+	if err := checkTmdbCacheHealth(gdb, report); err != nil {
+		return nil, errors.Wrap(err, "failed to check tmdb cache health")
+	}
+
+	return report, nil
+}
+
+// dedupeMediaItems groups MediaItem rows by imdb/tmdb id, keeps the row
+// with the most complete Data blob in each group, re-points any LocalFile
+// rows pointing at the rest, and deletes them.
+func dedupeMediaItems(gdb *gorm.DB, report *HousekeepReport) error {
+	var items []MediaItem
+	if res := gdb.Find(&items); res.Error != nil {
+		return res.Error
+	}
+
+	groups := map[string][]MediaItem{}
+	for _, item := range items {
+		key, ok := dedupeKey(item)
+		if !ok {
+			continue
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return len(group[i].Data) > len(group[j].Data)
+		})
+		survivor := group[0]
+		for _, loser := range group[1:] {
+			if res := gdb.Model(&LocalFile{}).Where("media_item_id = ?", loser.ID).
+				Update("media_item_id", survivor.ID); res.Error != nil {
+				return res.Error
+			}
+			if res := gdb.Delete(&MediaItem{}, "id = ?", loser.ID); res.Error != nil {
+				return res.Error
+			}
+			report.DuplicateMediaItemsMerged++
+		}
+	}
+	return nil
+}
+
+func dedupeKey(item MediaItem) (string, bool) {
+	var ids mediaItemIds
+	if err := json.Unmarshal(item.Data, &ids); err != nil {
+		return "", false
+	}
+	switch {
+	case ids.Ids.Imdb != "":
+		return "imdb:" + ids.Ids.Imdb, true
+	case ids.Ids.Tmdb != 0:
+		return fmt.Sprintf("tmdb:%d", ids.Ids.Tmdb), true
+	default:
+		return "", false
+	}
+}
+
+// pruneOrphanedLocalFiles removes LocalFile rows left behind by a
+// MediaItem that's since been deleted (by a prior dedupe pass, a manual
+// DB edit, or a provider that stopped tracking the item).
+func pruneOrphanedLocalFiles(gdb *gorm.DB, report *HousekeepReport) error {
+	res := gdb.Exec(`DELETE FROM local_files WHERE media_item_id NOT IN (SELECT id FROM media_items)`)
+	if res.Error != nil {
+		return res.Error
+	}
+	report.OrphanedLocalFilesRemoved = int(res.RowsAffected)
+	return nil
+}
+
+// pruneInvalidMediaAuth removes MediaAuth rows with an empty service_name,
+// and rows whose token has expired with no refresh token left to silently
+// renew it (so the row can't do anything but fail the next sync). Rows of
+// a provider that was removed or renamed keep their service name and are
+// not removed.
+func pruneInvalidMediaAuth(gdb *gorm.DB, report *HousekeepReport) error {
+	res := gdb.Delete(&MediaAuth{}, "service_name = ?", "")
+	if res.Error != nil {
+		return res.Error
+	}
+	report.InvalidMediaAuthRemoved = int(res.RowsAffected)
+
+	var candidates []MediaAuth
+	res = gdb.Where("service_name != ? AND refresh_token = ? AND token_expires_in > 0", "", "").Find(&candidates)
+	if res.Error != nil {
+		return res.Error
+	}
+	for _, auth := range candidates {
+		if !authTokenExpired(auth) {
+			continue
+		}
+		if res := gdb.Delete(&MediaAuth{}, "service_name = ?", auth.ServiceName); res.Error != nil {
+			return res.Error
+		}
+		report.InvalidMediaAuthRemoved++
+	}
+	return nil
+}
+
+// authTokenExpired mirrors traktv.tokenExpired's local expiry check
+// (TokenCreatedAt+TokenExpiresIn, with a 10 minute leeway) without
+// importing traktv, which already imports db.
+func authTokenExpired(auth MediaAuth) bool {
+	expiresAt := auth.TokenCreatedAt.Add(time.Duration(auth.TokenExpiresIn) * time.Second)
+	return time.Now().Add(10 * time.Minute).After(expiresAt)
+}
+
+// checkTmdbCacheHealth flags MediaItems with a tmdb id but no matching
+// cached TMDB response, meaning enrichWithTmdb never completed for them.
+func checkTmdbCacheHealth(gdb *gorm.DB, report *HousekeepReport) error {
+	var items []MediaItem
+	if res := gdb.Where("service_name = ?", "trakt").Find(&items); res.Error != nil {
+		return res.Error
+	}
+
+	for _, item := range items {
+		var ids mediaItemIds
+		if err := json.Unmarshal(item.Data, &ids); err != nil || ids.Ids.Tmdb == 0 {
+			continue
+		}
+
+		var count int64
+		res := gdb.Model(&CacheEntry{}).
+			Where("key IN ?", []string{
+				fmt.Sprintf("tmdb.movie.%d.en", ids.Ids.Tmdb),
+				fmt.Sprintf("tmdb.show.%d.en", ids.Ids.Tmdb),
+			}).
+			Count(&count)
+		if res.Error != nil {
+			return res.Error
+		}
+		if count == 0 {
+			report.MissingTmdbCacheEntries = append(report.MissingTmdbCacheEntries, item.ID)
+		}
+	}
+	return nil
+}
@@ -1,15 +1,42 @@
 package db
 
-import "gorm.io/datatypes"
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuthFlavor values understood by providers' Init, describing how ExtraData
+// should be interpreted.
+const (
+	AuthFlavorNone            = "none"
+	AuthFlavorPassword        = "password"
+	AuthFlavorOAuthDeviceCode = "oauth-device-code"
+	AuthFlavorCookieJar       = "cookie-jar"
+)
 
 type MediaAuth struct {
 	ServiceName string `gorm:"primaryKey"`
-	Token       string
-	Username    string
-	Password    string
-	APIKey      string
-	APISecret   string
-	ExtraData   datatypes.JSON
+	// AuthFlavor selects how a provider authenticates; see the
+	// AuthFlavor* constants. No default is set: each provider's
+	// AuthSeeder fills it in, and rows created before this column existed
+	// have it empty.
+	AuthFlavor string
+	Token      string
+	Username   string
+	Password   string
+	APIKey     string
+	APISecret  string
+	// RefreshToken, TokenCreatedAt, and TokenExpiresIn let a provider
+	// compute its own token's expiry locally and silently refresh it
+	// (see traktv.tokenExpired/refreshAccessToken) instead of checking
+	// with the API or dragging the user through a full re-auth.
+	RefreshToken   string
+	TokenCreatedAt time.Time
+	TokenExpiresIn int
+	// ExtraData holds auth-flavor-specific state: a cookie jar dump for
+	// AuthFlavorCookieJar, nothing for AuthFlavorNone, etc.
+	ExtraData datatypes.JSON
 }
 
 type MediaItem struct {
@@ -19,3 +46,42 @@ type MediaItem struct {
 	ServiceName string
 	Data        datatypes.JSON
 }
+
+// CacheEntry is a cached HTTP response body, keyed by a stable string such
+// as "tmdb.movie.<id>.<lang>", persisted by internal/cache's Postgres Store.
+type CacheEntry struct {
+	Key          string `gorm:"primaryKey"`
+	Value        []byte
+	ETag         string
+	LastModified string
+	ExpiresAt    time.Time
+}
+
+// LocalFile associates a file on disk (e.g. under a local movies/ or
+// shows/ library directory) with the MediaItem it was fuzzy-matched
+// against, populated by internal/media/watcher.
+type LocalFile struct {
+	ID          uint `gorm:"primaryKey"`
+	Path        string `gorm:"uniqueIndex"`
+	MediaItemID string `gorm:"index"`
+	MatchTitle  string
+	MatchYear   int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Job is a unit of background work enqueued onto the persistent job queue
+// (see internal/media/queue). Kind identifies the handler that should run
+// it, e.g. "refresh:trakt", "fetch:tmdb-details", "fetch:thumbnail".
+type Job struct {
+	ID          uint `gorm:"primaryKey"`
+	Kind        string
+	Payload     datatypes.JSON
+	Status      string `gorm:"index"`
+	Attempts    int
+	MaxAttempts int
+	NextRunAt   time.Time `gorm:"index"`
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
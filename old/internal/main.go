@@ -1,17 +1,79 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/skamensky/skam.dev/internal/cache"
 	"github.com/skamensky/skam.dev/internal/db"
 	"github.com/skamensky/skam.dev/internal/media"
+	"github.com/skamensky/skam.dev/internal/media/httpcache"
+	"github.com/skamensky/skam.dev/internal/media/traktv"
 )
 
 func main() {
+	housekeep := flag.Bool("housekeep", false, "run db.Housekeep against the MediaItem/MediaAuth tables and exit")
+	purgeType := flag.String("purge", "", "evict one cached entry and exit: movie, show, fanart-movie, or fanart-show (requires -purge-id)")
+	purgeID := flag.Int("purge-id", 0, "tmdb id (tvdb id for fanart-show) to evict, paired with -purge")
+	flag.Parse()
+
 	err := db.InitializeData()
 	if err != nil {
 		panic(err)
 	}
-	err = media.RefreshAllMediaItems()
+
+	if *housekeep {
+		gdb, err := db.NewDB()
+		if err != nil {
+			panic(err)
+		}
+		report, err := db.Housekeep(context.Background(), gdb)
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("housekeeping report: %+v", report)
+		return
+	}
+
+	if *purgeType != "" {
+		gdb, err := db.NewDB()
+		if err != nil {
+			panic(err)
+		}
+		store, err := traktv.NewCacheStore(gdb)
+		if err != nil {
+			panic(err)
+		}
+		if err := purgeCacheEntry(store, *purgeType, *purgeID); err != nil {
+			panic(err)
+		}
+		log.Printf("purged cached %s entry for id %d", *purgeType, *purgeID)
+		return
+	}
+
+	err = media.RefreshAllMediaItems(context.Background())
 	if err != nil {
 		panic(err)
 	}
 }
+
+// purgeCacheEntry dispatches a -purge/-purge-id pair to the matching
+// httpcache.Purge* helper, e.g. when an operator knows a specific TMDB
+// entry or its fanart is stale and wants it re-fetched on the next sync
+// without waiting out its TTL.
+func purgeCacheEntry(store cache.Store, purgeType string, id int) error {
+	switch purgeType {
+	case "movie":
+		return httpcache.PurgeMovie(store, id)
+	case "show":
+		return httpcache.PurgeShow(store, id)
+	case "fanart-movie":
+		return httpcache.PurgeFanartMovie(store, id)
+	case "fanart-show":
+		return httpcache.PurgeFanartShow(store, id)
+	default:
+		return fmt.Errorf("unknown -purge type %q (want movie, show, fanart-movie, or fanart-show)", purgeType)
+	}
+}
@@ -0,0 +1,182 @@
+// Package bilibili is a MediaItemList provider for Bilibili favorites and
+// watch-later lists, authenticated via a cookie jar (Bilibili has no public
+// OAuth flow) rather than the username/password or device-code flows the
+// other providers use.
+package bilibili
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+	"github.com/skamensky/skam.dev/internal/media/common"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const baseURL = "https://api.bilibili.com"
+
+func init() {
+	common.RegisterProvider("bilibili", func() common.MediaItemList {
+		return &BilibiliItemList{}
+	}, seedAuth)
+}
+
+// seedAuth builds the bilibili MediaAuth row from env vars. BILIBILI_COOKIES
+// is expected to be the JSON-encoded cookieJarData captured from a
+// logged-in browser session, since Bilibili has no password/API-key flow
+// for favorites.
+func seedAuth() []db.MediaAuth {
+	return []db.MediaAuth{
+		{
+			ServiceName: "bilibili",
+			AuthFlavor:  db.AuthFlavorCookieJar,
+			ExtraData:   datatypes.JSON(os.Getenv("BILIBILI_COOKIES")),
+		},
+	}
+}
+
+// cookieJarData is the shape stored in db.MediaAuth.ExtraData for the
+// db.AuthFlavorCookieJar flavor: the raw cookies captured from a logged-in
+// browser session (Bilibili has no API key/secret flow for favorites).
+type cookieJarData struct {
+	Cookies []*http.Cookie `json:"cookies"`
+	Mid     string         `json:"mid"` // the logged-in user's member id
+}
+
+type FavoriteItem struct {
+	BVID  string `json:"bvid"`
+	Title string `json:"title"`
+	Cover string `json:"cover"`
+}
+
+func (item *FavoriteItem) Id() string   { return item.BVID }
+func (item *FavoriteItem) Name() string { return item.Title }
+func (item *FavoriteItem) DownloadThumbnail() io.ReadCloser {
+	resp, err := http.Get(item.Cover)
+	if err != nil {
+		return nil
+	}
+	return resp.Body
+}
+
+type favoriteListResponse struct {
+	Data struct {
+		Medias []struct {
+			BVID  string `json:"bvid"`
+			Title string `json:"title"`
+			Cover string `json:"cover"`
+		} `json:"medias"`
+	} `json:"data"`
+}
+
+type BilibiliItemList struct {
+	db         *gorm.DB
+	httpClient *http.Client
+	authInfo   *db.MediaAuth
+	extra      cookieJarData
+	Items      []*FavoriteItem
+}
+
+func (list *BilibiliItemList) Init(gdb *gorm.DB) error {
+	list.db = gdb
+
+	var authInfo db.MediaAuth
+	res := list.db.Where("service_name = ?", "bilibili").First(&authInfo)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to load bilibili auth")
+	}
+	list.authInfo = &authInfo
+
+	if authInfo.AuthFlavor != db.AuthFlavorCookieJar {
+		return errors.Errorf("bilibili auth flavor must be %q, got %q", db.AuthFlavorCookieJar, authInfo.AuthFlavor)
+	}
+	if err := json.Unmarshal(authInfo.ExtraData, &list.extra); err != nil {
+		return errors.Wrap(err, "failed to parse bilibili cookie jar")
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cookie jar")
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	jar.SetCookies(base, list.extra.Cookies)
+	list.httpClient = &http.Client{Jar: jar}
+
+	return nil
+}
+
+func (list *BilibiliItemList) RetrieveItemsFromTracker() ([]common.MediaItem, error) {
+	reqURL := fmt.Sprintf("%s/x/v3/fav/resource/list?type=2&up_mid=%s&media_id=watchlater", baseURL, list.extra.Mid)
+	resp, err := list.httpClient.Get(reqURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch bilibili favorites")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bilibili response")
+	}
+
+	var parsed favoriteListResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, errors.Wrap(err, "failed to parse bilibili favorites response")
+	}
+
+	list.Items = make([]*FavoriteItem, 0, len(parsed.Data.Medias))
+	for _, media := range parsed.Data.Medias {
+		list.Items = append(list.Items, &FavoriteItem{
+			BVID:  media.BVID,
+			Title: media.Title,
+			Cover: media.Cover,
+		})
+	}
+
+	return list.asMediaItems(), nil
+}
+
+func (list *BilibiliItemList) RetrieveItemsFromDB() ([]common.MediaItem, error) {
+	return list.asMediaItems(), nil
+}
+
+func (list *BilibiliItemList) RetrievePrivateIdsFromDB() []string {
+	return nil
+}
+
+func (list *BilibiliItemList) PersistToDB() error {
+	list.db.Delete(&db.MediaItem{}, "service_name = ?", "bilibili")
+	for _, item := range list.Items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal bilibili item")
+		}
+		row := db.MediaItem{
+			ID:          item.Id(),
+			Name:        item.Name(),
+			ServiceName: "bilibili",
+			Data:        datatypes.JSON(data),
+		}
+		if res := list.db.Create(&row); res.Error != nil {
+			return errors.Wrap(res.Error, "failed to persist bilibili item")
+		}
+	}
+	return nil
+}
+
+func (list *BilibiliItemList) asMediaItems() []common.MediaItem {
+	items := make([]common.MediaItem, len(list.Items))
+	for i, item := range list.Items {
+		items[i] = item
+	}
+	return items
+}
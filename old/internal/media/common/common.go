@@ -2,6 +2,7 @@ package common
 
 import (
 	"github.com/skamensky/skam.dev/internal/db"
+	"github.com/skamensky/skam.dev/internal/media/queue"
 	"gorm.io/gorm"
 	"io"
 )
@@ -19,26 +20,153 @@ type MediaItem interface {
 	DownloadThumbnail() io.ReadCloser
 }
 
+// ThumbnailJobPayload is the queue.Job payload for a "fetch:thumbnail" job.
+type ThumbnailJobPayload struct {
+	MediaItemID string `json:"media_item_id"`
+}
+
+// TmdbDetailsJobPayload is the queue.Job payload for a "fetch:tmdb-details"
+// job: a retry of one item's TMDB lookup after it failed during its list's
+// last enrichment pass. MediaType is "movie" or "tv", matching TMDB's own
+// URL segments.
+type TmdbDetailsJobPayload struct {
+	TmdbID    int    `json:"tmdb_id"`
+	MediaType string `json:"media_type"`
+}
+
+// Factory builds a fresh, uninitialized MediaItemList for a provider. A
+// fresh instance is created per refresh cycle, the same way callers used to
+// write `traktv.TraktItemList{}` by hand.
+type Factory func() MediaItemList
+
+// AuthSeeder returns the db.MediaAuth row(s) a provider needs to run,
+// typically built from env vars. db.InitializeData used to hard-code this
+// per service (trakt, tmdb); now each provider owns its own seeding logic
+// and registers it alongside its Factory.
+type AuthSeeder func() []db.MediaAuth
+
+type registration struct {
+	factory Factory
+	seeder  AuthSeeder
+}
+
+var providers = map[string]registration{}
+
+// RegisterProvider makes a provider available to RefreshAllMediaItems, and
+// its auth row(s) available to EnsureAuthRows. Providers call this from an
+// init() func in their own package, e.g. traktv registers itself as
+// "trakt". Registering the same name twice is a programming error and
+// panics, matching how Go's database/sql drivers and image decoders
+// register themselves.
+func RegisterProvider(name string, factory Factory, seeder AuthSeeder) {
+	if _, exists := providers[name]; exists {
+		panic("common: provider already registered: " + name)
+	}
+	providers[name] = registration{factory: factory, seeder: seeder}
+}
+
+// Providers returns a fresh MediaItemList instance for every registered
+// provider.
+func Providers() []MediaItemList {
+	lists := make([]MediaItemList, 0, len(providers))
+	for _, reg := range providers {
+		lists = append(lists, reg.factory())
+	}
+	return lists
+}
+
+// EnsureAuthRows creates any db.MediaAuth row a registered provider needs
+// but that isn't in the DB yet, using that provider's AuthSeeder. This
+// replaces the trakt/tmdb-specific bootstrapping db.InitializeData used to
+// do directly.
+func EnsureAuthRows(gdb *gorm.DB) error {
+	var existing []db.MediaAuth
+	if res := gdb.Find(&existing); res.Error != nil {
+		return res.Error
+	}
+	have := map[string]bool{}
+	for _, auth := range existing {
+		have[auth.ServiceName] = true
+	}
+
+	for _, reg := range providers {
+		if reg.seeder == nil {
+			continue
+		}
+		for _, auth := range reg.seeder() {
+			if have[auth.ServiceName] {
+				continue
+			}
+			if res := gdb.Create(&auth); res.Error != nil {
+				return res.Error
+			}
+			have[auth.ServiceName] = true
+		}
+	}
+	return nil
+}
+
+// ProgressStage marks where in a single list's refresh cycle a
+// ProgressEvent was raised.
+type ProgressStage string
+
+const (
+	StageScanning   ProgressStage = "scanning"
+	StageEnriching  ProgressStage = "enriching"
+	StagePersisting ProgressStage = "persisting"
+)
+
+// ProgressEvent reports progress through RefreshListsWithProgress, e.g. for
+// a caller that wants to drive an observable FSM (see
+// internal/media/scheduler) instead of just blocking until everything's
+// done.
+type ProgressEvent struct {
+	ListIndex int
+	ListTotal int
+	Stage     ProgressStage
+	Message   string
+}
+
 func RefreshLists(lists []MediaItemList) error {
+	return RefreshListsWithProgress(lists, func(ProgressEvent) {})
+}
+
+// RefreshListsWithProgress is RefreshLists with onProgress called at each
+// stage of every list's refresh cycle.
+func RefreshListsWithProgress(lists []MediaItemList, onProgress func(ProgressEvent)) error {
 	DB, err := db.NewDB()
 	if err != nil {
 		return err
 	}
-	for _, list := range lists {
+	if err := EnsureAuthRows(DB); err != nil {
+		return err
+	}
+	q := queue.New(DB)
+	for i, list := range lists {
 		err := list.Init(DB)
 		if err != nil {
 			return err
 		}
+
+		onProgress(ProgressEvent{ListIndex: i, ListTotal: len(lists), Stage: StageScanning, Message: "fetching items from tracker"})
 		items, err := list.RetrieveItemsFromTracker()
-		// TODO, get all thumbnails for items
-		_ = items
 		if err != nil {
 			return err
 		}
+
+		onProgress(ProgressEvent{ListIndex: i, ListTotal: len(lists), Stage: StageEnriching, Message: "items enriched"})
+
+		onProgress(ProgressEvent{ListIndex: i, ListTotal: len(lists), Stage: StagePersisting, Message: "persisting to db"})
 		err = list.PersistToDB()
 		if err != nil {
 			return err
 		}
+		for _, item := range items {
+			err := q.Enqueue("fetch:thumbnail", ThumbnailJobPayload{MediaItemID: item.Id()})
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
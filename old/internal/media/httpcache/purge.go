@@ -0,0 +1,29 @@
+package httpcache
+
+import (
+	"fmt"
+
+	"github.com/skamensky/skam.dev/internal/cache"
+)
+
+// PurgeMovie evicts a single TMDB movie's cached details, keyed the same
+// way getMovieData writes them.
+func PurgeMovie(store cache.Store, tmdbId int) error {
+	return store.Purge(fmt.Sprintf("tmdb.movie.%v.en", tmdbId))
+}
+
+// PurgeShow evicts a single TMDB show's cached details.
+func PurgeShow(store cache.Store, tmdbId int) error {
+	return store.Purge(fmt.Sprintf("tmdb.show.%v.en", tmdbId))
+}
+
+// PurgeFanartMovie evicts a single movie's cached fanart.tv artwork.
+func PurgeFanartMovie(store cache.Store, tmdbId int) error {
+	return store.Purge(fmt.Sprintf("fanart.movie.%v", tmdbId))
+}
+
+// PurgeFanartShow evicts a single show's cached fanart.tv artwork, keyed
+// by TVDB id like getFanartShowData writes it.
+func PurgeFanartShow(store cache.Store, tvdbId int) error {
+	return store.Purge(fmt.Sprintf("fanart.show.%v", tvdbId))
+}
@@ -0,0 +1,114 @@
+// Package httpcache throttles the shared retryablehttp.Client used by
+// TraktItemList so a moderately sized library doesn't 429 TMDB or Trakt.
+// Response caching already lives in internal/cache (FSStore/PGStore, wired
+// up via traktv.doReqAndMarshalCached) from the earlier work to stop
+// re-fetching unchanged TMDB/Trakt records every sync; this package adds
+// the one thing that was still missing, per-host request throttling, plus
+// thin per-entity Purge helpers over that existing store.
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter is a simple token bucket: it holds at most burst tokens, refills
+// at ratePerSec, and Wait blocks until a token is available (or ctx is
+// done). It intentionally doesn't pull in a third-party rate limiting
+// library for something this small.
+type Limiter struct {
+	mu           sync.Mutex
+	tokens       float64
+	max          float64
+	refillPerSec float64
+	last         time.Time
+}
+
+func NewLimiter(ratePerSec float64, burst int) *Limiter {
+	return &Limiter{
+		tokens:       float64(burst),
+		max:          float64(burst),
+		refillPerSec: ratePerSec,
+		last:         time.Now(),
+	}
+}
+
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve()
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reserve refills the bucket for elapsed time, takes a token if one's
+// available, and otherwise reports how long the caller should wait before
+// trying again.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.last)
+	l.last = now
+	l.tokens += elapsed.Seconds() * l.refillPerSec
+	if l.tokens > l.max {
+		l.tokens = l.max
+	}
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	missing := 1 - l.tokens
+	return time.Duration(missing/l.refillPerSec*1000) * time.Millisecond
+}
+
+// Default per-host limits, per each API's documented rate limits: TMDB
+// allows ~40 requests/10s, Trakt ~1 request/s.
+const (
+	tmdbHost  = "api.themoviedb.org"
+	traktHost = "api.trakt.tv"
+)
+
+func defaultLimiters() map[string]*Limiter {
+	return map[string]*Limiter{
+		tmdbHost:  NewLimiter(4, 40),
+		traktHost: NewLimiter(1, 1),
+	}
+}
+
+// Transport wraps an http.RoundTripper and blocks each request until the
+// per-host Limiter for req.URL.Host has a token, falling through
+// unthrottled for any host it doesn't recognize (e.g. fanart.tv).
+type Transport struct {
+	next     http.RoundTripper
+	limiters map[string]*Limiter
+}
+
+// NewTransport wraps next (http.DefaultTransport if nil) with the default
+// TMDB/Trakt limiters.
+func NewTransport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, limiters: defaultLimiters()}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if limiter, ok := t.limiters[req.URL.Host]; ok {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
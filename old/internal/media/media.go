@@ -1,12 +1,66 @@
 package media
 
 import (
+	"context"
+	"log"
+
+	"github.com/skamensky/skam.dev/internal/db"
 	"github.com/skamensky/skam.dev/internal/media/common"
-	"github.com/skamensky/skam.dev/internal/media/traktv"
+	"github.com/skamensky/skam.dev/internal/media/watcher"
+
+	// Blank-imported so each provider's init() registers itself with
+	// common.RegisterProvider; RefreshAllMediaItems iterates whatever ends
+	// up registered rather than hard-coding a provider list.
+	_ "github.com/skamensky/skam.dev/internal/media/bilibili"
+	_ "github.com/skamensky/skam.dev/internal/media/podcast"
+	_ "github.com/skamensky/skam.dev/internal/media/traktv"
 )
 
-func RefreshAllMediaItems() error {
-	traktvvList := traktv.TraktItemList{}
-	err := common.RefreshLists([]common.MediaItemList{&traktvvList})
-	return err
+type options struct {
+	watchPaths []string
+}
+
+type Option func(*options)
+
+// WithWatch puts RefreshAllMediaItems into a long-running mode: after the
+// initial one-shot refresh it keeps running, watching paths for local
+// library changes and recording each matched file as a db.LocalFile row.
+// A match is only logged; it does not trigger a refresh of the item.
+func WithWatch(paths ...string) Option {
+	return func(o *options) {
+		o.watchPaths = append(o.watchPaths, paths...)
+	}
+}
+
+func RefreshAllMediaItems(ctx context.Context, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := common.RefreshLists(common.Providers()); err != nil {
+		return err
+	}
+
+	if len(o.watchPaths) == 0 {
+		return nil
+	}
+
+	gdb, err := db.NewDB()
+	if err != nil {
+		return err
+	}
+
+	w, err := watcher.New(gdb, func(mediaItemID string, path string) {
+		// A full RefreshLists pulls every provider's entire catalog; there's
+		// no per-item refresh hook yet, so for now a match just logs. Once
+		// providers support targeted refresh, trigger it here instead.
+		log.Printf("watcher: matched %s to media item %s", path, mediaItemID)
+	}, o.watchPaths...)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return w.Run(ctx)
 }
@@ -0,0 +1,161 @@
+// Package podcast is a generic RSS/Atom MediaItemList provider: point it at
+// any podcast feed URL and it tracks episodes the same way the trakt
+// provider tracks movies/shows. It requires no auth (db.AuthFlavorNone) —
+// podcast feeds are public.
+package podcast
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+	"github.com/skamensky/skam.dev/internal/media/common"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func init() {
+	common.RegisterProvider("podcast", func() common.MediaItemList {
+		return &PodcastItemList{}
+	}, seedAuth)
+}
+
+// seedAuth builds the podcast MediaAuth row from PODCAST_FEED_URLS, a
+// comma-separated list of feed URLs to poll.
+func seedAuth() []db.MediaAuth {
+	var feedURLs []string
+	if raw := os.Getenv("PODCAST_FEED_URLS"); raw != "" {
+		feedURLs = strings.Split(raw, ",")
+	}
+	extra, _ := json.Marshal(feedURLsData{FeedURLs: feedURLs})
+	return []db.MediaAuth{
+		{
+			ServiceName: "podcast",
+			AuthFlavor:  db.AuthFlavorNone,
+			ExtraData:   datatypes.JSON(extra),
+		},
+	}
+}
+
+// feedURLsData is the db.MediaAuth.ExtraData shape for the "podcast"
+// service row: a flat list of feed URLs to poll, since there's no single
+// account to authenticate against.
+type feedURLsData struct {
+	FeedURLs []string `json:"feed_urls"`
+}
+
+type Episode struct {
+	GUID         string `json:"guid"`
+	Title        string `json:"title"`
+	FeedTitle    string `json:"feed_title"`
+	ImageURL     string `json:"image_url"`
+	EnclosureURL string `json:"enclosure_url"`
+}
+
+func (e *Episode) Id() string   { return e.GUID }
+func (e *Episode) Name() string { return e.Title }
+func (e *Episode) DownloadThumbnail() io.ReadCloser {
+	if e.ImageURL == "" {
+		return nil
+	}
+	resp, err := http.Get(e.ImageURL)
+	if err != nil {
+		return nil
+	}
+	return resp.Body
+}
+
+type PodcastItemList struct {
+	db       *gorm.DB
+	parser   *gofeed.Parser
+	feedURLs []string
+	Episodes []*Episode
+}
+
+func (list *PodcastItemList) Init(gdb *gorm.DB) error {
+	list.db = gdb
+	list.parser = gofeed.NewParser()
+
+	var authInfo db.MediaAuth
+	res := list.db.Where("service_name = ?", "podcast").First(&authInfo)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to load podcast auth")
+	}
+	if authInfo.AuthFlavor != db.AuthFlavorNone {
+		return errors.Errorf("podcast auth flavor must be %q, got %q", db.AuthFlavorNone, authInfo.AuthFlavor)
+	}
+
+	var extra feedURLsData
+	if err := json.Unmarshal(authInfo.ExtraData, &extra); err != nil {
+		return errors.Wrap(err, "failed to parse podcast feed url list")
+	}
+	list.feedURLs = extra.FeedURLs
+
+	return nil
+}
+
+func (list *PodcastItemList) RetrieveItemsFromTracker() ([]common.MediaItem, error) {
+	list.Episodes = nil
+	for _, feedURL := range list.feedURLs {
+		feed, err := list.parser.ParseURL(feedURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse feed %s", feedURL)
+		}
+		for _, item := range feed.Items {
+			episode := &Episode{
+				GUID:      item.GUID,
+				Title:     item.Title,
+				FeedTitle: feed.Title,
+			}
+			if item.Image != nil {
+				episode.ImageURL = item.Image.URL
+			}
+			if len(item.Enclosures) > 0 {
+				episode.EnclosureURL = item.Enclosures[0].URL
+			}
+			list.Episodes = append(list.Episodes, episode)
+		}
+	}
+	return list.asMediaItems(), nil
+}
+
+func (list *PodcastItemList) RetrieveItemsFromDB() ([]common.MediaItem, error) {
+	return list.asMediaItems(), nil
+}
+
+func (list *PodcastItemList) RetrievePrivateIdsFromDB() []string {
+	return nil
+}
+
+func (list *PodcastItemList) PersistToDB() error {
+	list.db.Delete(&db.MediaItem{}, "service_name = ?", "podcast")
+	for _, episode := range list.Episodes {
+		data, err := json.Marshal(episode)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal podcast episode")
+		}
+		row := db.MediaItem{
+			ID:          episode.Id(),
+			Name:        episode.Name(),
+			ServiceName: "podcast",
+			Data:        datatypes.JSON(data),
+		}
+		if res := list.db.Create(&row); res.Error != nil {
+			return errors.Wrap(res.Error, "failed to persist podcast episode")
+		}
+	}
+	return nil
+}
+
+func (list *PodcastItemList) asMediaItems() []common.MediaItem {
+	items := make([]common.MediaItem, len(list.Episodes))
+	for i, episode := range list.Episodes {
+		items[i] = episode
+	}
+	return items
+}
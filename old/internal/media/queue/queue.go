@@ -0,0 +1,155 @@
+// Package queue implements a small, Postgres-backed background job queue
+// modeled after asynq-style workers. Jobs are rows in db.Job; producers call
+// Enqueue and one or more cmd/worker processes call Dequeue in a loop to
+// claim and execute them, retrying with exponential backoff on failure.
+package queue
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+const DefaultMaxAttempts = 5
+
+// baseBackoff is the delay before the first retry; each subsequent retry
+// doubles it, capped at maxBackoff.
+const baseBackoff = 30 * time.Second
+const maxBackoff = 30 * time.Minute
+
+type Queue struct {
+	db *gorm.DB
+}
+
+func New(gdb *gorm.DB) *Queue {
+	return &Queue{db: gdb}
+}
+
+// Enqueue schedules a job of the given kind to run as soon as a worker is
+// free. payload is marshalled to JSON and handed back to the handler
+// unmarshalled on dequeue.
+func (q *Queue) Enqueue(kind string, payload any) error {
+	return q.EnqueueAt(kind, payload, time.Now())
+}
+
+// EnqueueAt schedules a job that should not be picked up before runAt,
+// useful for periodic schedules.
+func (q *Queue) EnqueueAt(kind string, payload any, runAt time.Time) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal job payload")
+	}
+
+	job := db.Job{
+		Kind:        kind,
+		Payload:     raw,
+		Status:      StatusPending,
+		MaxAttempts: DefaultMaxAttempts,
+		NextRunAt:   runAt,
+	}
+	res := q.db.Create(&job)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to enqueue job")
+	}
+	return nil
+}
+
+// Dequeue claims the oldest due job, if any, marking it as running so no
+// other worker picks it up concurrently. It returns (nil, nil) when the
+// queue is empty.
+func (q *Queue) Dequeue() (*db.Job, error) {
+	var job db.Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", StatusPending, time.Now()).
+			Order("next_run_at asc").
+			Limit(1).
+			First(&job)
+		if goerrors.Is(res.Error, gorm.ErrRecordNotFound) {
+			job = db.Job{}
+			return nil
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+
+		job.Status = StatusRunning
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dequeue job")
+	}
+	if job.ID == 0 {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// Complete marks a successfully processed job as done.
+func (q *Queue) Complete(job *db.Job) error {
+	job.Status = StatusDone
+	res := q.db.Save(job)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to mark job done")
+	}
+	return nil
+}
+
+// Fail records a failed attempt. If the job still has attempts left it is
+// rescheduled with exponential backoff; otherwise it's parked as failed.
+func (q *Queue) Fail(job *db.Job, jobErr error) error {
+	job.Attempts++
+	job.LastError = jobErr.Error()
+
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = StatusFailed
+	} else {
+		job.Status = StatusPending
+		job.NextRunAt = time.Now().Add(backoffFor(job.Attempts))
+	}
+
+	res := q.db.Save(job)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to record job failure")
+	}
+	return nil
+}
+
+// Counts returns the number of jobs in each status, keyed by StatusPending
+// et al., for status-introspection callers (CLI reports, admin endpoints).
+func (q *Queue) Counts() (map[string]int64, error) {
+	statuses := []string{StatusPending, StatusRunning, StatusDone, StatusFailed}
+	counts := make(map[string]int64, len(statuses))
+	for _, status := range statuses {
+		var n int64
+		res := q.db.Model(&db.Job{}).Where("status = ?", status).Count(&n)
+		if res.Error != nil {
+			return nil, errors.Wrap(res.Error, "failed to count jobs")
+		}
+		counts[status] = n
+	}
+	return counts, nil
+}
+
+func backoffFor(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
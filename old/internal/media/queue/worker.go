@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+)
+
+// Handler processes the payload of a single job of a registered kind.
+type Handler func(payload json.RawMessage) error
+
+// Worker repeatedly dequeues and executes jobs, dispatching by Kind to a
+// registered Handler. A single failing job is retried on its own schedule
+// and never aborts the rest of the run.
+type Worker struct {
+	queue    *Queue
+	handlers map[string]Handler
+	// PollInterval is how long to sleep when the queue is empty.
+	PollInterval time.Duration
+}
+
+func NewWorker(q *Queue) *Worker {
+	return &Worker{
+		queue:        q,
+		handlers:     map[string]Handler{},
+		PollInterval: 5 * time.Second,
+	}
+}
+
+func (w *Worker) Register(kind string, h Handler) {
+	w.handlers[kind] = h
+}
+
+// Run dequeues and executes jobs until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		job, err := w.queue.Dequeue()
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.PollInterval):
+			}
+			continue
+		}
+
+		w.process(job)
+	}
+}
+
+func (w *Worker) process(job *db.Job) {
+	handler, ok := w.handlers[job.Kind]
+	if !ok {
+		_ = w.queue.Fail(job, errors.Errorf("no handler registered for job kind %q", job.Kind))
+		return
+	}
+
+	log.Printf("running job #%d (%s), attempt %d/%d", job.ID, job.Kind, job.Attempts+1, job.MaxAttempts)
+	if err := handler(json.RawMessage(job.Payload)); err != nil {
+		log.Printf("job #%d (%s) failed: %v", job.ID, job.Kind, err)
+		if failErr := w.queue.Fail(job, err); failErr != nil {
+			log.Printf("failed to record failure for job #%d: %v", job.ID, failErr)
+		}
+		return
+	}
+
+	if err := w.queue.Complete(job); err != nil {
+		log.Printf("failed to mark job #%d done: %v", job.ID, err)
+	}
+}
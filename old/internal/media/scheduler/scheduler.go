@@ -0,0 +1,196 @@
+// Package scheduler runs common.RefreshListsWithProgress on a repeating
+// interval and translates its ProgressEvents into an
+// idle -> scanning -> enriching -> persisting -> idle state machine, so a
+// dashboard can subscribe to (or poll) what a sync is doing instead of
+// just waiting on a blocking CLI-style call. This replaces ad hoc progress
+// tracking (e.g. the wg+channel fan-out in traktv.enrichWithTmdb) with one
+// place any provider's refresh cycle reports through.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/skamensky/skam.dev/internal/media/common"
+)
+
+// State is one node of the sync FSM.
+type State string
+
+const (
+	StateIdle       State = "idle"
+	StateScanning   State = "scanning"
+	StateEnriching  State = "enriching"
+	StatePersisting State = "persisting"
+)
+
+// Event drives transitions between States. Scheduler derives these itself
+// from common.ProgressEvent; callers never emit them directly.
+type Event string
+
+const (
+	EventImport  Event = "import"
+	EventFound   Event = "found"
+	EventScraped Event = "scraped"
+	EventFinish  Event = "finish"
+)
+
+// transitions is the FSM's edge table: transitions[state][event] is the
+// state it moves to next.
+var transitions = map[State]map[Event]State{
+	StateIdle:       {EventImport: StateScanning},
+	StateScanning:   {EventFound: StateEnriching},
+	StateEnriching:  {EventScraped: StatePersisting},
+	StatePersisting: {EventFinish: StateIdle},
+}
+
+// Context is the observable snapshot of a sync in progress, published to
+// every Subscribe()r and served at the Routes endpoint after each
+// transition.
+type Context struct {
+	State     State     `json:"state"`
+	Message   string    `json:"message"`
+	Progress  float64   `json:"progress"`
+	Completed bool      `json:"completed"`
+	Err       string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Scheduler drives common.RefreshListsWithProgress on a repeating
+// Interval, exposing the sync's current Context over Subscribe and Routes.
+// Only one sync runs at a time; Run blocks until ctx is cancelled.
+type Scheduler struct {
+	// Interval is how long to wait between sync cycles. Defaults to 1 hour.
+	Interval time.Duration
+
+	mu    sync.Mutex
+	state State
+	ctx   Context
+	subs  []chan Context
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		Interval: time.Hour,
+		state:    StateIdle,
+		ctx:      Context{State: StateIdle, Message: "waiting for first sync", UpdatedAt: time.Now()},
+	}
+}
+
+// Current returns the most recently published Context.
+func (s *Scheduler) Current() Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+// Subscribe returns a channel that receives every Context update for the
+// life of the Scheduler. The channel is buffered so a slow reader doesn't
+// stall the sync; a reader that stops draining it will eventually miss
+// updates rather than block one.
+func (s *Scheduler) Subscribe() <-chan Context {
+	ch := make(chan Context, 8)
+	s.mu.Lock()
+	s.subs = append(s.subs, ch)
+	s.mu.Unlock()
+	return ch
+}
+
+// Routes registers a GET endpoint at pattern returning Current() as JSON,
+// for a dashboard to poll without holding a subscription open.
+func (s *Scheduler) Routes(mux *http.ServeMux, pattern string) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Current())
+	})
+}
+
+// Run syncs every registered provider immediately, then again every
+// Interval, until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		if err := s.syncOnce(); err != nil {
+			log.Printf("scheduler: sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.Interval):
+		}
+	}
+}
+
+func (s *Scheduler) syncOnce() error {
+	s.advance(EventImport, "starting sync", 0)
+
+	err := common.RefreshListsWithProgress(common.Providers(), func(ev common.ProgressEvent) {
+		frac := float64(ev.ListIndex) / float64(ev.ListTotal)
+		switch ev.Stage {
+		case common.StageScanning:
+			s.advance(EventFound, fmt.Sprintf("list %d/%d: %s", ev.ListIndex+1, ev.ListTotal, ev.Message), frac)
+		case common.StageEnriching:
+			s.advance(EventScraped, fmt.Sprintf("list %d/%d: %s", ev.ListIndex+1, ev.ListTotal, ev.Message), frac)
+		case common.StagePersisting:
+			s.advance(EventFinish, fmt.Sprintf("list %d/%d: %s", ev.ListIndex+1, ev.ListTotal, ev.Message), frac)
+			// Back to scanning for the next list in the batch, if any;
+			// the final list's EventFinish above is what actually lands
+			// the FSM back on StateIdle.
+			if ev.ListIndex+1 < ev.ListTotal {
+				s.advance(EventImport, "starting next list", frac)
+			}
+		}
+	})
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+
+	s.publish(Context{State: StateIdle, Message: "sync complete", Progress: 1, Completed: true, UpdatedAt: time.Now()})
+	s.state = StateIdle
+	return nil
+}
+
+// advance walks the FSM edge table for the current state+event and
+// publishes the resulting Context. An event with no edge from the current
+// state is a programming error in syncOnce, not something callers can
+// trigger, so it panics rather than silently staying put.
+func (s *Scheduler) advance(event Event, message string, progress float64) {
+	s.mu.Lock()
+	next, ok := transitions[s.state][event]
+	if !ok {
+		s.mu.Unlock()
+		panic(fmt.Sprintf("scheduler: no transition for event %q from state %q", event, s.state))
+	}
+	s.state = next
+	s.mu.Unlock()
+
+	s.publish(Context{State: next, Message: message, Progress: progress, UpdatedAt: time.Now()})
+}
+
+func (s *Scheduler) fail(err error) {
+	s.mu.Lock()
+	s.state = StateIdle
+	s.mu.Unlock()
+	s.publish(Context{State: StateIdle, Message: "sync failed", Err: err.Error(), UpdatedAt: time.Now()})
+}
+
+func (s *Scheduler) publish(ctx Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	subs := s.subs
+	s.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- ctx:
+		default:
+			// Slow subscriber; drop this update rather than block the sync.
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import "testing"
+
+func TestAdvanceWalksFullSyncCycle(t *testing.T) {
+	s := New()
+	sub := s.Subscribe()
+
+	steps := []struct {
+		event Event
+		want  State
+	}{
+		{EventImport, StateScanning},
+		{EventFound, StateEnriching},
+		{EventScraped, StatePersisting},
+		{EventFinish, StateIdle},
+	}
+
+	for _, step := range steps {
+		s.advance(step.event, "test", 0)
+
+		if got := s.Current().State; got != step.want {
+			t.Fatalf("after advance(%q): Current().State = %q, want %q", step.event, got, step.want)
+		}
+
+		select {
+		case ctx := <-sub:
+			if ctx.State != step.want {
+				t.Fatalf("advance(%q) published state %q, want %q", step.event, ctx.State, step.want)
+			}
+		default:
+			t.Fatalf("advance(%q) did not publish an update to the subscriber", step.event)
+		}
+	}
+}
+
+func TestAdvancePanicsOnEventWithNoEdgeFromCurrentState(t *testing.T) {
+	s := New() // starts in StateIdle, which only has an edge for EventImport
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("advance with no transition for the current state should panic")
+		}
+	}()
+	s.advance(EventScraped, "should not happen", 0)
+}
+
+func TestTransitionsTable(t *testing.T) {
+	cases := []struct {
+		state     State
+		event     Event
+		wantState State
+		wantOk    bool
+	}{
+		{StateIdle, EventImport, StateScanning, true},
+		{StateScanning, EventFound, StateEnriching, true},
+		{StateEnriching, EventScraped, StatePersisting, true},
+		{StatePersisting, EventFinish, StateIdle, true},
+		// Every state accepts exactly one event; every other event has no edge.
+		{StateIdle, EventFound, "", false},
+		{StateIdle, EventScraped, "", false},
+		{StateIdle, EventFinish, "", false},
+		{StateScanning, EventImport, "", false},
+		{StateEnriching, EventFound, "", false},
+		{StatePersisting, EventScraped, "", false},
+	}
+
+	for _, c := range cases {
+		got, ok := transitions[c.state][c.event]
+		if ok != c.wantOk {
+			t.Errorf("transitions[%q][%q]: ok = %v, want %v", c.state, c.event, ok, c.wantOk)
+			continue
+		}
+		if ok && got != c.wantState {
+			t.Errorf("transitions[%q][%q] = %q, want %q", c.state, c.event, got, c.wantState)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package traktv
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AuthNotifier shows the user a Trakt device-flow verification URL and
+// code so they can approve the sign-in from a browser. Implementations
+// might log it, post it to a webhook, or surface it as a web-UI toast.
+type AuthNotifier interface {
+	Notify(verificationURL, userCode string) error
+}
+
+// LogNotifier is the default AuthNotifier: it just logs the code.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(verificationURL, userCode string) error {
+	log.Printf("Trakt sign-in required: open %s and enter code %s", verificationURL, userCode)
+	return nil
+}
+
+// runDeviceFlow requests a device code, shows it to the user via
+// list.AuthNotifier, and polls /oauth/device/token at the interval Trakt
+// specifies until the user approves it or the code expires.
+func (list *TraktItemList) runDeviceFlow() error {
+	codeReq := prepareTraktReq(
+		"POST",
+		"/oauth/device/code",
+		map[string]string{
+			"client_id": list.authInfo.APIKey,
+		},
+		list.authInfo,
+	)
+
+	devCodeResp := &traktDeviceCodeResponse{}
+	if err := doReqAndMarshal(codeReq, list.httpClient, devCodeResp); err != nil {
+		return errors.Wrap(err, "failed to request trakt device code")
+	}
+
+	notifier := list.AuthNotifier
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	if err := notifier.Notify(devCodeResp.VerificationURL, devCodeResp.UserCode); err != nil {
+		return errors.Wrap(err, "failed to notify user of trakt device code")
+	}
+
+	tokenResp, err := list.pollDeviceToken(devCodeResp)
+	if err != nil {
+		return err
+	}
+	return list.saveTokenResponse(tokenResp)
+}
+
+// pollDeviceToken polls /oauth/device/token at devCodeResp.Interval until
+// the user approves the code (200), the code expires, or an
+// unrecoverable error comes back. A 400 ("authorization_pending") just
+// means keep waiting; that's the only status this loop treats as
+// "try again".
+func (list *TraktItemList) pollDeviceToken(devCodeResp *traktDeviceCodeResponse) (*traktTokenResponse, error) {
+	deadline := time.Now().Add(time.Duration(devCodeResp.ExpiresIn) * time.Second)
+	interval := time.Duration(devCodeResp.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		if time.Now().After(deadline) {
+			return nil, errors.New("trakt device code expired before it was approved")
+		}
+
+		tokenReq := prepareTraktReq(
+			"POST",
+			"/oauth/device/token",
+			map[string]string{
+				"code":          devCodeResp.DeviceCode,
+				"client_id":     list.authInfo.APIKey,
+				"client_secret": list.authInfo.APISecret,
+			},
+			list.authInfo,
+		)
+		resp, err := list.httpClient.Do(tokenReq)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to poll trakt device token endpoint")
+		}
+
+		switch resp.StatusCode {
+		case 200:
+			data, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read trakt device token response")
+			}
+			tokenResp := &traktTokenResponse{}
+			if err := json.Unmarshal(data, tokenResp); err != nil {
+				return nil, errors.Wrap(err, "failed to parse trakt device token response")
+			}
+			return tokenResp, nil
+		case 400:
+			// authorization_pending: user hasn't approved yet.
+			resp.Body.Close()
+			time.Sleep(interval)
+		default:
+			resp.Body.Close()
+			return nil, errors.Errorf("unexpected status %d polling trakt device token endpoint", resp.StatusCode)
+		}
+	}
+}
+
+// refreshAccessToken silently exchanges list.authInfo's refresh token for
+// a new access token via grant_type=refresh_token, avoiding a trip back
+// through the device flow.
+func (list *TraktItemList) refreshAccessToken() error {
+	tokenReq := prepareTraktReq(
+		"POST",
+		"/oauth/token",
+		map[string]string{
+			"refresh_token": list.authInfo.RefreshToken,
+			"client_id":     list.authInfo.APIKey,
+			"client_secret": list.authInfo.APISecret,
+			"grant_type":    "refresh_token",
+		},
+		list.authInfo,
+	)
+	tokenResp := &traktTokenResponse{}
+	if err := doReqAndMarshal(tokenReq, list.httpClient, tokenResp); err != nil {
+		return errors.Wrap(err, "failed to refresh trakt access token")
+	}
+	return list.saveTokenResponse(tokenResp)
+}
+
+func (list *TraktItemList) saveTokenResponse(tokenResp *traktTokenResponse) error {
+	list.authInfo.Token = tokenResp.AccessToken
+	list.authInfo.RefreshToken = tokenResp.RefreshToken
+	list.authInfo.TokenCreatedAt = time.Unix(int64(tokenResp.CreatedAt), 0)
+	list.authInfo.TokenExpiresIn = tokenResp.ExpiresIn
+	if res := list.db.Save(&list.authInfo); res.Error != nil {
+		return errors.Wrap(res.Error, "failed to persist trakt token")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package traktv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// tmdbImageBaseURL is a fallback for when fanart.tv has nothing for an
+// item; enrichWithTmdb's own /configuration call resolves the real base
+// url/size, but this fixed "original" size is good enough as a fallback.
+const tmdbImageBaseURL = "https://image.tmdb.org/t/p/original"
+
+// fanartImage is one entry from fanart.tv's per-type image arrays (e.g.
+// "movieposter", "hdtvlogo").
+type fanartImage struct {
+	URL   string `json:"url"`
+	Likes string `json:"likes"`
+}
+
+// FanartImages holds fanart.tv artwork for a movie or show. Each slice is
+// sorted by likes descending, so [0] is the fan-curated "best" pick.
+type FanartImages struct {
+	Posters     []fanartImage `json:"posters,omitempty"`
+	Backgrounds []fanartImage `json:"backgrounds,omitempty"`
+	Logos       []fanartImage `json:"logos,omitempty"`
+}
+
+// PosterURL returns the best available poster, preferring fanart.tv's
+// fan-curated artwork and falling back to the TMDB poster (tmdbPosterPath
+// as returned by TmdbMovie/TmdbShow.PosterPath) when fanart has nothing,
+// including when fanart wasn't configured at all.
+func (f *FanartImages) PosterURL(tmdbPosterPath string) string {
+	if f != nil && len(f.Posters) > 0 {
+		return f.Posters[0].URL
+	}
+	if tmdbPosterPath == "" {
+		return ""
+	}
+	return tmdbImageBaseURL + tmdbPosterPath
+}
+
+type fanartMovieResponse struct {
+	Name            string        `json:"name"`
+	TmdbID          string        `json:"tmdb_id"`
+	ImdbID          string        `json:"imdb_id"`
+	MoviePoster     []fanartImage `json:"movieposter"`
+	MovieBackground []fanartImage `json:"moviebackground"`
+	HdMovieLogo     []fanartImage `json:"hdmovielogo"`
+}
+
+type fanartShowResponse struct {
+	Name           string        `json:"name"`
+	TvdbID         string        `json:"thetvdb_id"`
+	TvPoster       []fanartImage `json:"tvposter"`
+	ShowBackground []fanartImage `json:"showbackground"`
+	HdTvLogo       []fanartImage `json:"hdtvlogo"`
+}
+
+func sortByLikes(images []fanartImage) []fanartImage {
+	sorted := make([]fanartImage, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return likesOf(sorted[i]) > likesOf(sorted[j])
+	})
+	return sorted
+}
+
+func likesOf(img fanartImage) int {
+	n, _ := strconv.Atoi(img.Likes)
+	return n
+}
+
+// fanartResult pairs a fanart response with the id it was fetched for, so
+// the caller can build a lookup map once every fanout goroutine finishes.
+type fanartResult struct {
+	id     int
+	images *FanartImages
+}
+
+// getFanartMovieData fetches fanart.tv artwork for a movie by its TMDB id.
+// It degrades to sending a nil result (not an error) whenever fanart isn't
+// configured, matching "skip gracefully when no API key is configured".
+func (list *TraktItemList) getFanartMovieData(tmdbId int, fanartChan chan<- *fanartResult, errorChan chan<- error) {
+	if list.fanartAuthInfo == nil || list.fanartAuthInfo.APIKey == "" {
+		fanartChan <- nil
+		return
+	}
+
+	req := prepareFanartReq(fmt.Sprintf("/movies/%v", tmdbId), list.fanartAuthInfo)
+	resp := &fanartMovieResponse{}
+	key := fmt.Sprintf("fanart.movie.%v", tmdbId)
+	if err := doReqAndMarshalCached(req, list.httpClient, list.cacheStore, key, fanartTTL, resp); err != nil {
+		errorChan <- err
+		fanartChan <- nil
+		return
+	}
+
+	fanartChan <- &fanartResult{
+		id: tmdbId,
+		images: &FanartImages{
+			Posters:     sortByLikes(resp.MoviePoster),
+			Backgrounds: sortByLikes(resp.MovieBackground),
+			Logos:       sortByLikes(resp.HdMovieLogo),
+		},
+	}
+}
+
+// getFanartShowData is getFanartMovieData for shows, keyed by TVDB id
+// (fanart.tv's /v3/tv endpoint, unlike /v3/movies, is keyed by TheTVDB,
+// not TMDB).
+func (list *TraktItemList) getFanartShowData(tvdbId int, fanartChan chan<- *fanartResult, errorChan chan<- error) {
+	if list.fanartAuthInfo == nil || list.fanartAuthInfo.APIKey == "" || tvdbId == 0 {
+		fanartChan <- nil
+		return
+	}
+
+	req := prepareFanartReq(fmt.Sprintf("/tv/%v", tvdbId), list.fanartAuthInfo)
+	resp := &fanartShowResponse{}
+	key := fmt.Sprintf("fanart.show.%v", tvdbId)
+	if err := doReqAndMarshalCached(req, list.httpClient, list.cacheStore, key, fanartTTL, resp); err != nil {
+		errorChan <- err
+		fanartChan <- nil
+		return
+	}
+
+	fanartChan <- &fanartResult{
+		id: tvdbId,
+		images: &FanartImages{
+			Posters:     sortByLikes(resp.TvPoster),
+			Backgrounds: sortByLikes(resp.ShowBackground),
+			Logos:       sortByLikes(resp.HdTvLogo),
+		},
+	}
+}
@@ -0,0 +1,180 @@
+package traktv
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/skamensky/skam.dev/internal/cache"
+)
+
+// negativeResolveTTL is how long a "couldn't find a TMDB match" result is
+// cached for, so a show Trakt legitimately has no tmdb id for isn't
+// re-queried on every sync. Positive matches get the longer
+// tmdbDetailsTTL, same as everything else fetched from TMDB.
+const negativeResolveTTL = 24 * time.Hour
+
+type tmdbFindResponse struct {
+	MovieResults []TmdbMovie `json:"movie_results"`
+	TvResults    []TmdbShow  `json:"tv_results"`
+}
+
+type tmdbSearchMovieResponse struct {
+	Results []TmdbMovie `json:"results"`
+}
+
+type tmdbSearchShowResponse struct {
+	Results []TmdbShow `json:"results"`
+}
+
+// resolvedTmdbId is what resolver lookups cache; ID==0 means "confirmed
+// not found", distinct from "never looked up" (a cache miss).
+type resolvedTmdbId struct {
+	ID int `json:"id"`
+}
+
+// resolveTmdbId fills in a TMDB id for a Trakt entry missing one, trying
+// identifiers in order of reliability: TVDB (tv only), then IMDB, then
+// falling back to a title+year search as a last resort. mediaType is
+// "movie" or "tv", matching TMDB's /find and /search path segments.
+func (list *TraktItemList) resolveTmdbId(entry TraktEntry, mediaType string) (int, error) {
+	if mediaType == "tv" && entry.Ids.Tvdb != 0 {
+		if id, ok, err := list.findByExternalId(fmt.Sprintf("%d", entry.Ids.Tvdb), "tvdb_id", mediaType); err != nil {
+			return 0, err
+		} else if ok {
+			return id, nil
+		}
+	}
+
+	if entry.Ids.Imdb != "" {
+		if id, ok, err := list.findByExternalId(entry.Ids.Imdb, "imdb_id", mediaType); err != nil {
+			return 0, err
+		} else if ok {
+			return id, nil
+		}
+	}
+
+	return list.searchByTitleYear(entry.Title, entry.Year, mediaType)
+}
+
+// resolveMissingTmdbId fills in entry.Ids.Tmdb in place when the Trakt
+// item didn't come with one, logging and leaving it zero on failure
+// rather than aborting the enrichment for the whole item.
+func (list *TraktItemList) resolveMissingTmdbId(entry *TraktEntry, mediaType string) {
+	if entry.Ids.Tmdb != 0 {
+		return
+	}
+	id, err := list.resolveTmdbId(*entry, mediaType)
+	if err != nil {
+		log.Printf("failed to resolve tmdb id for %q: %v", entry.Title, err)
+		return
+	}
+	entry.Ids.Tmdb = id
+}
+
+// findByExternalId calls TMDB's /find/{externalId} with the given
+// external_source, consulting (and populating) the resolved-id cache
+// first so a permanently-unmatched item isn't retried every sync.
+func (list *TraktItemList) findByExternalId(externalId, source, mediaType string) (id int, found bool, err error) {
+	key := fmt.Sprintf("tmdb.resolve.%s.%s.%s", mediaType, source, externalId)
+	if cached, ok := list.getCachedResolvedId(key); ok {
+		return cached, cached != 0, nil
+	}
+
+	findReq := prepareTmdbReq(fmt.Sprintf("/find/%s?external_source=%s", externalId, source), list.tmdbAuthInfo)
+	resp := &tmdbFindResponse{}
+	if err := doReqAndMarshal(findReq, list.httpClient, resp); err != nil {
+		return 0, false, err
+	}
+
+	var resolvedId int
+	switch mediaType {
+	case "movie":
+		if len(resp.MovieResults) > 0 {
+			resolvedId = resp.MovieResults[0].ID
+		}
+	case "tv":
+		if len(resp.TvResults) > 0 {
+			resolvedId = resp.TvResults[0].ID
+		}
+	}
+
+	list.cacheResolvedId(key, resolvedId)
+	return resolvedId, resolvedId != 0, nil
+}
+
+// searchByTitleYear is the last-resort fallback: a plain title search,
+// filtered by year when we have one, taking the top result.
+func (list *TraktItemList) searchByTitleYear(title string, year int, mediaType string) (int, error) {
+	key := fmt.Sprintf("tmdb.resolve.%s.search.%s.%d", mediaType, title, year)
+	if cached, ok := list.getCachedResolvedId(key); ok {
+		return cached, nil
+	}
+
+	query := url.QueryEscape(title)
+	var resolvedId int
+
+	switch mediaType {
+	case "movie":
+		searchURL := fmt.Sprintf("/search/movie?query=%s", query)
+		if year != 0 {
+			searchURL += fmt.Sprintf("&year=%d", year)
+		}
+		resp := &tmdbSearchMovieResponse{}
+		req := prepareTmdbReq(searchURL, list.tmdbAuthInfo)
+		if err := doReqAndMarshal(req, list.httpClient, resp); err != nil {
+			return 0, err
+		}
+		if len(resp.Results) > 0 {
+			resolvedId = resp.Results[0].ID
+		}
+	case "tv":
+		searchURL := fmt.Sprintf("/search/tv?query=%s", query)
+		if year != 0 {
+			searchURL += fmt.Sprintf("&first_air_date_year=%d", year)
+		}
+		resp := &tmdbSearchShowResponse{}
+		req := prepareTmdbReq(searchURL, list.tmdbAuthInfo)
+		if err := doReqAndMarshal(req, list.httpClient, resp); err != nil {
+			return 0, err
+		}
+		if len(resp.Results) > 0 {
+			resolvedId = resp.Results[0].ID
+		}
+	}
+
+	list.cacheResolvedId(key, resolvedId)
+	return resolvedId, nil
+}
+
+func (list *TraktItemList) getCachedResolvedId(key string) (int, bool) {
+	if list.cacheStore == nil {
+		return 0, false
+	}
+	entry, err := list.cacheStore.Get(key)
+	if err != nil || entry.Expired() {
+		return 0, false
+	}
+	var cached resolvedTmdbId
+	if err := json.Unmarshal(entry.Value, &cached); err != nil {
+		return 0, false
+	}
+	return cached.ID, true
+}
+
+func (list *TraktItemList) cacheResolvedId(key string, id int) {
+	if list.cacheStore == nil {
+		return
+	}
+	data, err := json.Marshal(resolvedTmdbId{ID: id})
+	if err != nil {
+		return
+	}
+	ttl := tmdbDetailsTTL
+	if id == 0 {
+		ttl = negativeResolveTTL
+	}
+	list.cacheStore.Set(key, &cache.Entry{Value: data, ExpiresAt: time.Now().Add(ttl)})
+}
@@ -0,0 +1,59 @@
+package traktv
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// ScrobbleAction is one of Trakt's three scrobble actions.
+type ScrobbleAction string
+
+const (
+	ScrobbleStart ScrobbleAction = "start"
+	ScrobblePause ScrobbleAction = "pause"
+	ScrobbleStop  ScrobbleAction = "stop"
+)
+
+type scrobbleBody struct {
+	Movie    *scrobbleEntry `json:"movie,omitempty"`
+	Show     *scrobbleEntry `json:"show,omitempty"`
+	Progress float64        `json:"progress"`
+}
+
+type scrobbleEntry struct {
+	Title string   `json:"title,omitempty"`
+	Year  int      `json:"year,omitempty"`
+	Ids   TraktIds `json:"ids"`
+}
+
+type scrobbleResponse struct {
+	Action string `json:"action"`
+	Movie  *TmdbMovie
+	Show   *TmdbShow
+}
+
+// Scrobble reports a play/pause/stop event for entry to Trakt, built on
+// prepareTraktJSONReq, the JSON-body variant of the prepareTraktReq helper
+// the rest of the package uses. entry.Ids
+// must have at least one of Imdb/Tmdb/Trakt set so Trakt can resolve the
+// item.
+func (list *TraktItemList) Scrobble(ctx context.Context, entry *TraktEntry, progress float64, action ScrobbleAction) error {
+	body := scrobbleBody{
+		Progress: progress,
+	}
+	target := &scrobbleEntry{Title: entry.Title, Year: entry.Year, Ids: entry.Ids}
+	if entry.Ids.Trakt != 0 || entry.Ids.Imdb != "" || entry.Ids.Tmdb != 0 {
+		// Every entry is sent as a movie. Shows are scrobbled per-episode
+		// in Trakt's API; episodes are not modeled here, so a show entry
+		// is not sent as a show.
+		body.Movie = target
+	}
+
+	req := prepareTraktJSONReq("POST", "/scrobble/"+string(action), body, list.authInfo).WithContext(ctx)
+	resp := &scrobbleResponse{}
+	if err := doReqAndMarshal(req, list.httpClient, resp); err != nil {
+		return errors.Wrapf(err, "failed to scrobble %s", action)
+	}
+	return nil
+}
@@ -2,35 +2,88 @@ package traktv
 
 import (
 	"fmt"
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/input"
-	"github.com/go-rod/rod/lib/launcher"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/skamensky/skam.dev/internal/cache"
 	"github.com/skamensky/skam.dev/internal/db"
 	"github.com/skamensky/skam.dev/internal/media/common"
+	"github.com/skamensky/skam.dev/internal/media/httpcache"
+	"github.com/skamensky/skam.dev/internal/media/queue"
 	"gorm.io/gorm"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
+	"time"
 )
 
+// Cache TTLs: movie/show metadata changes rarely, watched/watchlist state
+// changes every sync.
+const (
+	tmdbDetailsTTL = 7 * 24 * time.Hour
+	traktSyncTTL   = 5 * time.Minute
+	fanartTTL      = 7 * 24 * time.Hour
+)
+
+func init() {
+	common.RegisterProvider("trakt", func() common.MediaItemList {
+		return &TraktItemList{}
+	}, seedAuth)
+}
+
+// seedAuth builds the trakt, tmdb, and fanart MediaAuth rows from env vars,
+// the way db.InitializeData used to do directly for every service. The
+// fanart row's APIKey is left blank if FANART_API_KEY isn't set; fanart
+// artwork is skipped gracefully in that case rather than failing a sync.
+func seedAuth() []db.MediaAuth {
+	return []db.MediaAuth{
+		{
+			ServiceName: "trakt",
+			AuthFlavor:  db.AuthFlavorOAuthDeviceCode,
+			APIKey:      os.Getenv("TRAKT_API_KEY"),
+			APISecret:   os.Getenv("TRAKT_SECRET"),
+			Username:    os.Getenv("TRAKT_EMAIL"),
+			Password:    os.Getenv("TRAKT_PASSWORD"),
+		},
+		{
+			ServiceName: "tmdb",
+			AuthFlavor:  db.AuthFlavorNone,
+			APISecret:   os.Getenv("TMDB_SECRET"),
+		},
+		{
+			ServiceName: "fanart",
+			AuthFlavor:  db.AuthFlavorNone,
+			APIKey:      os.Getenv("FANART_API_KEY"),
+		},
+	}
+}
+
 var TRAKT_BASE_URL = "https://api.trakt.tv"
 var TMDB_BASE_URL = "https://api.themoviedb.org/3"
+var FANART_BASE_URL = "https://webservice.fanart.tv/v3"
 
 type TraktItemList struct {
-	db            *gorm.DB
-	httpClient    *retryablehttp.Client
-	authInfo      *db.MediaAuth
-	tmdbAuthInfo  *db.MediaAuth
+	db             *gorm.DB
+	httpClient     *retryablehttp.Client
+	cacheStore     cache.Store
+	authInfo       *db.MediaAuth
+	tmdbAuthInfo   *db.MediaAuth
+	fanartAuthInfo *db.MediaAuth
+	// queue is set by Init and used to retry a single item's failed TMDB
+	// lookup (see enqueueTmdbDetailsRetry) instead of discarding it; left
+	// nil for a TraktItemList built outside a full refresh cycle, e.g. by
+	// NewTmdbFetcher for a fetch:tmdb-details retry job.
+	queue *queue.Queue
+	// AuthNotifier is how the user is shown the device code during the
+	// Trakt device-flow sign-in. Defaults to LogNotifier if left nil.
+	AuthNotifier  AuthNotifier
 	WatchedMovies []*WatchedMovie
 	ToWatchMovies []*ToWatchMovie
 	WatchedShows  []*WatchedShow
 }
 
-func (list *TraktItemList) GetAllAsMediaItems() []d
-+b.MediaItem {
-	retVal := make([]common.MediaItem, len(list.WatchedShows)+len(list.WatchedMovies)+len(list.ToWatchMovies))
+func (list *TraktItemList) GetAllAsMediaItems() []common.MediaItem {
+	retVal := make([]common.MediaItem, 0, len(list.WatchedShows)+len(list.WatchedMovies)+len(list.ToWatchMovies))
 	for _, movie := range list.WatchedMovies {
 		retVal = append(retVal, &movie.Movie)
 	}
@@ -43,65 +96,41 @@ func (list *TraktItemList) GetAllAsMediaItems() []d
 	return retVal
 }
 
+// setAccessToken makes sure list.authInfo has a usable access token,
+// refreshing or re-running the device flow as needed:
+//   - token present and not expired (checked locally via tokenExpired): done
+//   - token expired but we have a refresh token: silently refresh it
+//   - no usable token at all: run the device-code flow via list.AuthNotifier
 func (list *TraktItemList) setAccessToken() error {
 	res := list.db.Where("service_name = ?", "trakt").First(&list.authInfo)
 	if res.Error != nil {
 		return res.Error
 	}
 
-	expired := false
-	if list.authInfo.Token == "" {
-		expired = true
-	} else {
-		tokenExpiredRes, err := tokenExpired(list.httpClient, list.authInfo)
-		if err != nil {
-			return err
-		}
-		expired = tokenExpiredRes
+	if list.authInfo.Token != "" && !tokenExpired(list.authInfo) {
+		return nil
 	}
 
-	if expired {
-		codeReq := prepareTraktReq(
-			"POST",
-			"/oauth/device/code",
-			map[string]string{
-				"client_id": list.authInfo.APIKey,
-			},
-			list.authInfo,
-		)
-
-		devCodeResp := &traktDeviceCodeResponse{}
-		err := doReqAndMarshal(codeReq, list.httpClient, devCodeResp)
-		if err != nil {
-			return err
-		}
-		err = list.authorizeUsingBrowser(devCodeResp)
-		if err != nil {
-			return err
-		}
-		tokenReq := prepareTraktReq(
-			"POST",
-			"/oauth/device/token",
-			map[string]string{
-				"code":          devCodeResp.DeviceCode,
-				"client_id":     list.authInfo.APIKey,
-				"client_secret": list.authInfo.APISecret,
-			},
-			list.authInfo,
-		)
-		tokenResp := &traktTokenResponse{}
-		err = doReqAndMarshal(tokenReq, list.httpClient, tokenResp)
-		if err != nil {
-			return err
-		}
-		list.authInfo.Token = tokenResp.AccessToken
-		saveRes := list.db.Save(&list.authInfo)
-		if saveRes.Error != nil {
-			return saveRes.Error
+	if list.authInfo.Token != "" && list.authInfo.RefreshToken != "" {
+		if err := list.refreshAccessToken(); err == nil {
+			return nil
 		}
+		// refresh failed (refresh token itself expired/revoked) - fall
+		// through to the full device flow below.
 	}
 
-	return nil
+	return list.runDeviceFlow()
+}
+
+// tokenExpired computes expiry locally from TokenCreatedAt+TokenExpiresIn,
+// as requested instead of spending an API call on /users/settings to find
+// out. A 10 minute leeway avoids running into an expired token mid-sync.
+func tokenExpired(authInfo *db.MediaAuth) bool {
+	if authInfo.TokenExpiresIn == 0 {
+		return true
+	}
+	expiresAt := authInfo.TokenCreatedAt.Add(time.Duration(authInfo.TokenExpiresIn) * time.Second)
+	return time.Now().Add(10 * time.Minute).After(expiresAt)
 }
 
 func (list *TraktItemList) PersistToDB() error {
@@ -125,7 +154,7 @@ func (list *TraktItemList) RetrieveItemsFromTracker() ([]common.MediaItem, error
 		list.authInfo,
 	)
 	watchedMovies := []*TraktWatchedMovie{}
-	err := doReqAndMarshal(watchedMoviesReq, list.httpClient, &watchedMovies)
+	err := doReqAndMarshalCached(watchedMoviesReq, list.httpClient, list.cacheStore, "trakt.watched.movies", traktSyncTTL, &watchedMovies)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +167,7 @@ func (list *TraktItemList) RetrieveItemsFromTracker() ([]common.MediaItem, error
 		list.authInfo,
 	)
 	watchedShows := []*TraktWatchedShow{}
-	err = doReqAndMarshal(watchedShowsReq, list.httpClient, &watchedShows)
+	err = doReqAndMarshalCached(watchedShowsReq, list.httpClient, list.cacheStore, "trakt.watched.shows", traktSyncTTL, &watchedShows)
 	if err != nil {
 		return nil, err
 	}
@@ -146,7 +175,7 @@ func (list *TraktItemList) RetrieveItemsFromTracker() ([]common.MediaItem, error
 	log.Println("Getting movies on watchlist")
 	toWatchMovies := []*TraktToWatchMovie{}
 	toWatchMoviesReq := prepareTraktReq("GET", "/sync/watchlist/movies/added", nil, list.authInfo)
-	err = doReqAndMarshal(toWatchMoviesReq, list.httpClient, &toWatchMovies)
+	err = doReqAndMarshalCached(toWatchMoviesReq, list.httpClient, list.cacheStore, "trakt.watchlist.movies", traktSyncTTL, &toWatchMovies)
 	if err != nil {
 		return nil, err
 	}
@@ -165,107 +194,152 @@ func (list *TraktItemList) RetrievePrivateIdsFromDB() []string {
 func (list *TraktItemList) Init(db *gorm.DB) error {
 
 	list.db = db
+	list.queue = queue.New(db)
 	list.httpClient = retryablehttp.NewClient()
+	// Throttle outgoing requests per-host so a large library's fanout in
+	// enrichWithTmdb doesn't blow through TMDB's/Trakt's rate limits.
+	list.httpClient.HTTPClient.Transport = httpcache.NewTransport(list.httpClient.HTTPClient.Transport)
 	// this is a static api token, no auth required
 	res := list.db.Where("service_name = ?", "tmdb").First(&list.tmdbAuthInfo)
 	if res.Error != nil {
 		return res.Error
 	}
+
+	// fanart is optional: if the row is missing or its key was never
+	// configured, enrichWithTmdb just skips fetching fanart artwork.
+	list.db.Where("service_name = ?", "fanart").First(&list.fanartAuthInfo)
+
+	cacheStore, err := NewCacheStore(db)
+	if err != nil {
+		return err
+	}
+	list.cacheStore = cacheStore
+
 	return list.setAccessToken()
 
 }
 
-func tokenExpired(httpClient *retryablehttp.Client, authInfo *db.MediaAuth) (bool, error) {
-	// todo, use the expired at data provided from the response to check this before doing a request
-	//  the reason I'm not doing that now is because the db table storing auth data is generic
-	//	 and doesn't have a column for traktv specific data
-
-	/*
-		expiresAt := time.Unix(int64(t.CreatedAt), 0).Add(time.Duration(t.ExpiresIn) * time.Second).In(time.UTC)
-		// give ourselves a 10 minutes leeway so we don't run into an expired token while processing
-		in10Minutes := time.Now().In(time.UTC).Add(10 * time.Minute)
-		// pessimistic check
-		officiallyExpired := expiresAt.Before(in10Minutes)
-		if officiallyExpired {
-			return true, nil
-		}
-	*/
+// NewCacheStore builds the response cache backend selected by the
+// CACHE_BACKEND env var ("postgres", the default, or "fs"). Exported so
+// callers outside this package (e.g. a CLI purge flag) can reach the same
+// backend traktv itself reads from.
+func NewCacheStore(db *gorm.DB) (cache.Store, error) {
+	if os.Getenv("CACHE_BACKEND") == "fs" {
+		return cache.NewFSStore()
+	}
+	return cache.NewPGStore(db), nil
+}
 
-	req := prepareTraktReq("GET", "/users/settings", nil, authInfo)
-	resp, err := httpClient.Do(req)
+// NewTmdbFetcher builds a TraktItemList configured only for TMDB lookups
+// (tmdb auth row + cache store, no Trakt device-flow auth), for a
+// fetch:tmdb-details job's RetryTmdbDetails call, which only needs
+// fetchTmdbMovie/fetchTmdbShow and never touches list.WatchedMovies et al.
+func NewTmdbFetcher(gdb *gorm.DB) (*TraktItemList, error) {
+	list := &TraktItemList{db: gdb, httpClient: retryablehttp.NewClient()}
+	list.httpClient.HTTPClient.Transport = httpcache.NewTransport(list.httpClient.HTTPClient.Transport)
+	if res := list.db.Where("service_name = ?", "tmdb").First(&list.tmdbAuthInfo); res.Error != nil {
+		return nil, res.Error
+	}
+	cacheStore, err := NewCacheStore(gdb)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	actuallyExpired := resp.StatusCode != 200
-	return actuallyExpired, nil
+	list.cacheStore = cacheStore
+	return list, nil
 }
 
-func (list *TraktItemList) authorizeUsingBrowser(devCodeResp *traktDeviceCodeResponse) (err error) {
-	defer func() {
-		// sorry, but this is just much nicer than checking 23 errors
-		if r := recover(); r != nil {
-			err = fmt.Errorf("error while doing browser authorization: %v", r)
+// RetryTmdbDetails re-runs a single item's TMDB lookup for a fetch:tmdb-details
+// job. The item's in-memory WatchedMovie/WatchedShow is long gone by the
+// time this job runs, so the result is written directly onto whichever
+// GORM table has a matching row: TmdbMovie/TmdbShow's own "ID" field is
+// promoted into WatchedMovie/ToWatchMovie/WatchedShow as their primary key,
+// so it's enough to address the row by tmdb id without the original
+// TraktItemList around. A movie id may legitimately exist in both
+// watched_movies and to_watch_movies (e.g. rewatched); both are updated.
+func (list *TraktItemList) RetryTmdbDetails(payload common.TmdbDetailsJobPayload) error {
+	if payload.MediaType == "tv" {
+		show, err := list.fetchTmdbShow(payload.TmdbID)
+		if err != nil {
+			return err
 		}
-	}()
-	lncher := launcher.MustNewManaged("")
-	log.Println("Connecting to remote browser")
-	//u := launcher.MustResolveURL("")
-	browser := rod.New().Client(lncher.MustClient()).MustConnect()
-	//browser := rod.New().ControlURL(u).MustConnect().MustIncognito()
-	// for debugging:
-	//launcher.Open(browser.ServeMonitor(""))
-	log.Println("Loading trakt.tv signin page")
-
-	pg := browser.MustPage("https://trakt.tv/auth/signin")
-
-	log.Println("Waiting for login form to load")
-	pg.MustWaitLoad()
-	pg.MustWaitElementsMoreThan("input[name='user[login]']", 0)
-	log.Println("Page loaded. Attempting to log in.")
-	pg.MustElement("input[name='user[login]']").MustInput(list.authInfo.Username)
-	pg.MustElement("input[name='user[password]']").MustInput(list.authInfo.Password).MustType(input.Enter)
-	log.Println("Waiting for login to redirect")
-	pg.MustWaitElementsMoreThan("a[href='/logout']", 0)
-	log.Println("Logged in. Navigating to device code page")
-	pg.MustNavigate(devCodeResp.VerificationURL)
-	log.Println("Waiting for device code to load")
-	pg.MustWaitLoad()
-	log.Println("Device code loaded. Attempting to enter device code")
-	pg.MustWaitElementsMoreThan("input[id='code']", 0)
-	log.Println("Entered device code, waiting for confirmation screen")
-	pg.MustElement("input[id='code']").MustInput(devCodeResp.UserCode).MustType(input.Enter)
-	pg.MustWaitElementsMoreThan("input[value=Yes]", 0)
-	log.Println("Confirming...")
-	pg.MustElement("input[value=Yes]").MustClick()
-	pg.MustWaitElementsMoreThan("div.approved", 0)
-	log.Println("Confirmed!")
-	browser.MustClose()
-
-	return
+		return list.db.Model(&WatchedShow{}).Where("id = ?", payload.TmdbID).Updates(show).Error
+	}
+
+	movie, err := list.fetchTmdbMovie(payload.TmdbID)
+	if err != nil {
+		return err
+	}
+	if res := list.db.Model(&WatchedMovie{}).Where("id = ?", payload.TmdbID).Updates(movie); res.Error != nil {
+		return res.Error
+	}
+	return list.db.Model(&ToWatchMovie{}).Where("id = ?", payload.TmdbID).Updates(movie).Error
 }
 
-func (list *TraktItemList) getMovieData(tmdbId int, movieChan chan<- *TmdbMovie, errorChan chan<- error) {
+// fetchTmdbMovie does the actual TMDB /movie/{id} lookup, synchronously,
+// shared by the goroutine-fanout path (getMovieData) and the single-item
+// fetch:tmdb-details retry path (RetryTmdbDetails). A tmdbId of 0 means
+// resolveMissingTmdbId already tried every fallback and there's nothing
+// left to fetch; that's not an error.
+func (list *TraktItemList) fetchTmdbMovie(tmdbId int) (*TmdbMovie, error) {
+	if tmdbId == 0 {
+		return nil, nil
+	}
 	movieReq := prepareTmdbReq(fmt.Sprintf("/movie/%v", tmdbId), list.tmdbAuthInfo)
 	movie := &TmdbMovie{}
-	err := doReqAndMarshal(movieReq, list.httpClient, movie)
-	if err != nil {
-		errorChan <- err
-	} else {
-		movieChan <- movie
+	key := fmt.Sprintf("tmdb.movie.%v.en", tmdbId)
+	if err := doReqAndMarshalCached(movieReq, list.httpClient, list.cacheStore, key, tmdbDetailsTTL, movie); err != nil {
+		return nil, err
 	}
-
+	return movie, nil
 }
 
-func (list *TraktItemList) getShowData(tmdbId int, showChan chan<- *TmdbShow, errorChan chan<- error) {
+// fetchTmdbShow is fetchTmdbMovie for TMDB's /tv/{id} endpoint.
+func (list *TraktItemList) fetchTmdbShow(tmdbId int) (*TmdbShow, error) {
+	if tmdbId == 0 {
+		return nil, nil
+	}
 	showReq := prepareTmdbReq(fmt.Sprintf("/tv/%v", tmdbId), list.tmdbAuthInfo)
 	show := &TmdbShow{}
-	err := doReqAndMarshal(showReq, list.httpClient, show)
+	key := fmt.Sprintf("tmdb.show.%v.en", tmdbId)
+	if err := doReqAndMarshalCached(showReq, list.httpClient, list.cacheStore, key, tmdbDetailsTTL, show); err != nil {
+		return nil, err
+	}
+	return show, nil
+}
+
+// enqueueTmdbDetailsRetry schedules a fetch:tmdb-details job for an item
+// whose TMDB lookup just failed, so it's retried on the queue's own
+// backoff schedule instead of being silently dropped for the rest of this
+// sync's lifetime. list.queue is nil for a TraktItemList built outside a
+// full refresh cycle (there's nothing to retry into in that case).
+func (list *TraktItemList) enqueueTmdbDetailsRetry(tmdbId int, mediaType string) {
+	if list.queue == nil || tmdbId == 0 {
+		return
+	}
+	payload := common.TmdbDetailsJobPayload{TmdbID: tmdbId, MediaType: mediaType}
+	if err := list.queue.Enqueue("fetch:tmdb-details", payload); err != nil {
+		log.Printf("failed to enqueue tmdb-details retry for tmdb id %d: %v", tmdbId, err)
+	}
+}
+
+func (list *TraktItemList) getMovieData(tmdbId int, movieChan chan<- *TmdbMovie, errorChan chan<- error) {
+	movie, err := list.fetchTmdbMovie(tmdbId)
 	if err != nil {
 		errorChan <- err
-	} else {
-		showChan <- show
+		list.enqueueTmdbDetailsRetry(tmdbId, "movie")
+		return
 	}
+	movieChan <- movie
+}
 
+func (list *TraktItemList) getShowData(tmdbId int, showChan chan<- *TmdbShow, errorChan chan<- error) {
+	show, err := list.fetchTmdbShow(tmdbId)
+	if err != nil {
+		errorChan <- err
+		list.enqueueTmdbDetailsRetry(tmdbId, "tv")
+		return
+	}
+	showChan <- show
 }
 
 func (entry *TraktEntry) GetThumbnail(httpClient *http.Client, url string, key string) error {
@@ -282,7 +356,7 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 	log.Println("Getting movie details")
 	configReq := prepareTmdbReq("/configuration", list.tmdbAuthInfo)
 	config := &tmdbConfig{}
-	err := doReqAndMarshal(configReq, list.httpClient, config)
+	err := doReqAndMarshalCached(configReq, list.httpClient, list.cacheStore, "tmdb.configuration", tmdbDetailsTTL, config)
 	if err != nil {
 		return err
 	}
@@ -291,7 +365,12 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 	watchedShowChan := make(chan *TmdbShow, len(watchedShowsTrakt))
 	toWatchMovieChan := make(chan *TmdbMovie, len(toWatchMoviesTrakt))
 
-	numPossibleErrors := len(watchedMoviesTrakt) + len(watchedShowsTrakt) + len(toWatchMoviesTrakt)
+	watchedMovieFanartChan := make(chan *fanartResult, len(watchedMoviesTrakt))
+	watchedShowFanartChan := make(chan *fanartResult, len(watchedShowsTrakt))
+	toWatchMovieFanartChan := make(chan *fanartResult, len(toWatchMoviesTrakt))
+
+	// x2: every item fans out a TMDB fetch and a fanart.tv fetch.
+	numPossibleErrors := 2 * (len(watchedMoviesTrakt) + len(watchedShowsTrakt) + len(toWatchMoviesTrakt))
 	errorChan := make(chan error, numPossibleErrors)
 
 	image_base_url := config.Images.SecureBaseURL + config.Images.PosterSizes[len(config.Images.PosterSizes)-1]
@@ -304,6 +383,7 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 		wg.Add(1)
 		go func(movie *TraktWatchedMovie) {
 			defer wg.Done()
+			list.resolveMissingTmdbId(&movie.Movie, "movie")
 			list.getMovieData(movie.Movie.Ids.Tmdb, watchedMovieChan, errorChan)
 		}(movie)
 	}
@@ -311,6 +391,7 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 		wg.Add(1)
 		go func(show *TraktWatchedShow) {
 			defer wg.Done()
+			list.resolveMissingTmdbId(&show.Show, "tv")
 			list.getShowData(show.Show.Ids.Tmdb, watchedShowChan, errorChan)
 		}(show)
 	}
@@ -318,15 +399,43 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 		wg.Add(1)
 		go func(movie *TraktToWatchMovie) {
 			defer wg.Done()
+			list.resolveMissingTmdbId(&movie.Movie, "movie")
 			list.getMovieData(movie.Movie.Ids.Tmdb, toWatchMovieChan, errorChan)
 		}(movie)
 	}
 
+	// Fanart.tv runs in the same fanout; getFanart*Data skips gracefully
+	// (sends a nil result, not an error) when fanart isn't configured.
+	for _, movie := range watchedMoviesTrakt {
+		wg.Add(1)
+		go func(movie *TraktWatchedMovie) {
+			defer wg.Done()
+			list.getFanartMovieData(movie.Movie.Ids.Tmdb, watchedMovieFanartChan, errorChan)
+		}(movie)
+	}
+	for _, show := range watchedShowsTrakt {
+		wg.Add(1)
+		go func(show *TraktWatchedShow) {
+			defer wg.Done()
+			list.getFanartShowData(show.Show.Ids.Tvdb, watchedShowFanartChan, errorChan)
+		}(show)
+	}
+	for _, movie := range toWatchMoviesTrakt {
+		wg.Add(1)
+		go func(movie *TraktToWatchMovie) {
+			defer wg.Done()
+			list.getFanartMovieData(movie.Movie.Ids.Tmdb, toWatchMovieFanartChan, errorChan)
+		}(movie)
+	}
+
 	wg.Wait()
 	close(errorChan)
 	close(watchedMovieChan)
 	close(watchedShowChan)
 	close(toWatchMovieChan)
+	close(watchedMovieFanartChan)
+	close(watchedShowFanartChan)
+	close(toWatchMovieFanartChan)
 
 	watchedMovies := []*TmdbMovie{}
 	for i := 0; i < len(watchedMovieChan); i++ {
@@ -337,19 +446,45 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 	tmdbIdToShow := make(map[int]*TmdbShow)
 
 	for show := range watchedShowChan {
-		tmdbIdToShow[show.ID] = show
+		if show != nil {
+			tmdbIdToShow[show.ID] = show
+		}
 	}
 	for movie := range toWatchMovieChan {
-		tmdbIdToMovie[movie.ID] = movie
+		if movie != nil {
+			tmdbIdToMovie[movie.ID] = movie
+		}
 	}
 	for movie := range watchedMovieChan {
-		tmdbIdToMovie[movie.ID] = movie
+		if movie != nil {
+			tmdbIdToMovie[movie.ID] = movie
+		}
+	}
+
+	tmdbIdToFanart := make(map[int]*FanartImages)
+	tvdbIdToFanart := make(map[int]*FanartImages)
+
+	for result := range watchedMovieFanartChan {
+		if result != nil {
+			tmdbIdToFanart[result.id] = result.images
+		}
+	}
+	for result := range toWatchMovieFanartChan {
+		if result != nil {
+			tmdbIdToFanart[result.id] = result.images
+		}
+	}
+	for result := range watchedShowFanartChan {
+		if result != nil {
+			tvdbIdToFanart[result.id] = result.images
+		}
 	}
 
 	for _, movie := range watchedMoviesTrakt {
 		list.WatchedMovies = append(list.WatchedMovies, &WatchedMovie{
 			TmdbMovie:         tmdbIdToMovie[movie.Movie.Ids.Tmdb],
 			TraktWatchedMovie: movie,
+			FanartImages:      tmdbIdToFanart[movie.Movie.Ids.Tmdb],
 		})
 	}
 
@@ -357,6 +492,7 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 		list.ToWatchMovies = append(list.ToWatchMovies, &ToWatchMovie{
 			TmdbMovie:         tmdbIdToMovie[movie.Movie.Ids.Tmdb],
 			TraktToWatchMovie: movie,
+			FanartImages:      tmdbIdToFanart[movie.Movie.Ids.Tmdb],
 		})
 	}
 
@@ -364,6 +500,7 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 		list.WatchedShows = append(list.WatchedShows, &WatchedShow{
 			TmdbShow:         tmdbIdToShow[show.Show.Ids.Tmdb],
 			TraktWatchedShow: show,
+			FanartImages:     tvdbIdToFanart[show.Show.Ids.Tvdb],
 		})
 	}
 
@@ -381,17 +518,19 @@ func (list *TraktItemList) enrichWithTmdb(watchedMoviesTrakt []*TraktWatchedMovi
 		show.TotalEpisodes = total
 		show.EpisodesWatched = episodesWatched
 	}
-	errorMessages := []string{}
-	for i := 0; i < len(errorChan); i++ {
-		err := <-errorChan
+	// Per-item TMDB/fanart failures above already got a fetch:tmdb-details
+	// retry job (or, for fanart, degrade gracefully on their own) instead of
+	// failing this function outright: one bad lookup in a library of
+	// hundreds shouldn't throw away every other item's enrichment and abort
+	// the whole refresh cycle. errorChan is only drained here for logging.
+	var errorMessages []string
+	for err := range errorChan {
 		if err != nil {
 			errorMessages = append(errorMessages, err.Error())
 		}
 	}
-
-	if len(errorChan) > 0 {
-		return fmt.Errorf("Number of errors: %d, all errors: %s", len(errorChan), strings.Join(errorMessages, ","))
-	} else {
-		return nil
+	if len(errorMessages) > 0 {
+		log.Printf("enrichWithTmdb: %d per-item lookup(s) failed (failed TMDB lookups were queued for retry, fanart ones were not): %s", len(errorMessages), strings.Join(errorMessages, "; "))
 	}
+	return nil
 }
@@ -24,6 +24,7 @@ type TraktIds struct {
 	Slug  string `json:"slug,omitempty"`
 	Imdb  string `json:"imdb,omitempty"`
 	Tmdb  int    `json:"tmdb,omitempty"`
+	Tvdb  int    `json:"tvdb,omitempty"`
 }
 type TraktEntry struct {
 	parent *TraktItemList
@@ -217,15 +218,18 @@ type WatchedShow struct {
 	TotalEpisodes   int
 	EpisodesWatched int
 	Progress        float64
+	FanartImages    *FanartImages
 }
 type WatchedMovie struct {
 	*TraktWatchedMovie
 	*TmdbMovie
+	FanartImages *FanartImages
 }
 
 type ToWatchMovie struct {
 	*TraktToWatchMovie
 	*TmdbMovie
+	FanartImages *FanartImages
 }
 
 func (s *TmdbShow) GetPosterKey() string {
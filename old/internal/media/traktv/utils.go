@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/skamensky/skam.dev/internal/cache"
 	"github.com/skamensky/skam.dev/internal/db"
 	"io/ioutil"
 	"log"
+	"time"
 )
 
 func prepareTmdbReq(url string, authInfo *db.MediaAuth) *retryablehttp.Request {
@@ -25,6 +27,21 @@ func prepareTmdbReq(url string, authInfo *db.MediaAuth) *retryablehttp.Request {
 	return req
 }
 
+func prepareFanartReq(url string, authInfo *db.MediaAuth) *retryablehttp.Request {
+	req_url := FANART_BASE_URL + url + "?api_key=" + authInfo.APIKey
+	req, err := retryablehttp.NewRequest(
+		"GET",
+		req_url,
+		nil,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
 func doReq(req *retryablehttp.Request, client *retryablehttp.Client) ([]byte, error) {
 	resp, err := client.Do(req)
 	if err != nil {
@@ -52,6 +69,92 @@ func doReqAndMarshal[T any](req *retryablehttp.Request, client *retryablehttp.Cl
 	return nil
 }
 
+// doReqAndMarshalCached is doReqAndMarshal with a cache.Store consulted
+// first. On a fresh cache hit the request is never sent. On a stale hit the
+// request is reissued with If-None-Match/If-Modified-Since so the origin
+// can reply 304 and we just refresh the entry's TTL instead of re-fetching
+// the body. store may be nil, in which case this behaves like
+// doReqAndMarshal.
+func doReqAndMarshalCached[T any](req *retryablehttp.Request, client *retryablehttp.Client, store cache.Store, key string, ttl time.Duration, obj *T) error {
+	if store == nil {
+		return doReqAndMarshal(req, client, obj)
+	}
+
+	entry, err := store.Get(key)
+	if err != nil && err != cache.ErrNotFound {
+		return err
+	}
+	if err == nil && !entry.Expired() {
+		return json.Unmarshal(entry.Value, obj)
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 304 && entry != nil {
+		entry.ExpiresAt = time.Now().Add(ttl)
+		if err := store.Set(key, entry); err != nil {
+			return err
+		}
+		return json.Unmarshal(entry.Value, obj)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		log.Printf("Failed json data: %v\n", string(data))
+		return err
+	}
+
+	newEntry := &cache.Entry{
+		Value:        data,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	return store.Set(key, newEntry)
+}
+
+// prepareTraktJSONReq is prepareTraktReq for callers that need an arbitrary
+// JSON request body (prepareTraktReq only accepts map[string]string, which
+// can't represent Scrobble's nested {movie:{ids:{...}}} shape).
+func prepareTraktJSONReq(method string, url string, body any, authInfo *db.MediaAuth) *retryablehttp.Request {
+	reqURL := TRAKT_BASE_URL + url
+	req, err := retryablehttp.NewRequest(method, reqURL, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("trakt-api-version", "2")
+	req.Header.Set("trakt-api-key", authInfo.APIKey)
+	if body != nil {
+		marshalled, err := json.Marshal(body)
+		if err != nil {
+			panic(err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewBuffer(marshalled))
+	}
+	if authInfo.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+authInfo.Token)
+	}
+	return req
+}
+
 func prepareTraktReq(method string, url string, jsonBody map[string]string, authInfo *db.MediaAuth) *retryablehttp.Request {
 	req_url := TRAKT_BASE_URL + url
 	req, err := retryablehttp.NewRequest(
@@ -0,0 +1,192 @@
+// Package watcher observes a local media library directory (movies/,
+// shows/) and fuzzy-matches newly added files against known MediaItem rows,
+// so the module can notice a new rip has landed without waiting for the
+// next full refresh cycle.
+package watcher
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+	"gorm.io/gorm"
+)
+
+// OnMatch is called whenever a local file is matched (or rematched) to a
+// MediaItem, so the caller can trigger a targeted refresh instead of
+// waiting for the next full library pull.
+type OnMatch func(mediaItemID string, path string)
+
+type Watcher struct {
+	db      *gorm.DB
+	fsw     *fsnotify.Watcher
+	onMatch OnMatch
+}
+
+// New creates a Watcher over the given directories. Call Run to start
+// processing events; Close stops watching.
+func New(gdb *gorm.DB, onMatch OnMatch, paths ...string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create fsnotify watcher")
+	}
+	for _, path := range paths {
+		if err := fsw.Add(path); err != nil {
+			fsw.Close()
+			return nil, errors.Wrapf(err, "failed to watch %s", path)
+		}
+	}
+	return &Watcher{db: gdb, fsw: fsw, onMatch: onMatch}, nil
+}
+
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
+
+// Run processes filesystem events until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("watcher error: %v", err)
+		}
+	}
+}
+
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	switch {
+	case event.Op&(fsnotify.Create|fsnotify.Rename) != 0:
+		if err := w.match(event.Name); err != nil {
+			log.Printf("failed to match %s: %v", event.Name, err)
+		}
+	case event.Op&fsnotify.Remove != 0:
+		if res := w.db.Delete(&db.LocalFile{}, "path = ?", event.Name); res.Error != nil {
+			log.Printf("failed to remove local file record for %s: %v", event.Name, res.Error)
+		}
+	}
+}
+
+func (w *Watcher) match(path string) error {
+	title, year := parseFilename(path)
+
+	var candidates []db.MediaItem
+	if res := w.db.Find(&candidates); res.Error != nil {
+		return errors.Wrap(res.Error, "failed to load media items for matching")
+	}
+
+	bestID := ""
+	bestScore := 0
+	for _, candidate := range candidates {
+		score := fuzzyScore(title, candidate.Name)
+		if score > bestScore {
+			bestScore = score
+			bestID = candidate.ID
+		}
+	}
+	if bestID == "" {
+		log.Printf("no media item match for %s (parsed title %q)", path, title)
+		return nil
+	}
+
+	localFile := db.LocalFile{
+		Path:        path,
+		MediaItemID: bestID,
+		MatchTitle:  title,
+		MatchYear:   year,
+	}
+	res := w.db.Where("path = ?", path).Assign(localFile).FirstOrCreate(&localFile)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "failed to persist local file match")
+	}
+
+	if w.onMatch != nil {
+		w.onMatch(bestID, path)
+	}
+	return nil
+}
+
+var yearRe = regexp.MustCompile(`\((\d{4})\)|\.(\d{4})\.|\s(\d{4})\s`)
+
+// parseFilename extracts a guessed title and release year from a local
+// filename such as "The.Matrix.1999.1080p.mkv" or "The Matrix (1999)/".
+func parseFilename(path string) (title string, year int) {
+	base := strings.TrimRight(path, "/\\")
+	if idx := strings.LastIndexAny(base, "/\\"); idx >= 0 {
+		base = base[idx+1:]
+	}
+	if idx := strings.LastIndex(base, "."); idx > 0 {
+		base = base[:idx]
+	}
+
+	if m := yearRe.FindStringSubmatch(base); m != nil {
+		for _, g := range m[1:] {
+			if g != "" {
+				year, _ = strconv.Atoi(g)
+				base = base[:strings.Index(base, g)]
+				break
+			}
+		}
+	}
+
+	base = strings.NewReplacer(".", " ", "_", " ").Replace(base)
+	return strings.TrimSpace(strings.TrimRight(base, " (")), year
+}
+
+// fuzzyScore is a small normalized-token-overlap scorer: no external fuzzy
+// matching dependency, just case/punctuation-insensitive word overlap,
+// which is enough to tell "The Matrix" from "The Matrix Reloaded".
+func fuzzyScore(a, b string) int {
+	aTokens := tokenize(a)
+	bTokens := tokenize(b)
+	if len(aTokens) == 0 || len(bTokens) == 0 {
+		return 0
+	}
+
+	bSet := make(map[string]bool, len(bTokens))
+	for _, t := range bTokens {
+		bSet[t] = true
+	}
+
+	matches := 0
+	for _, t := range aTokens {
+		if bSet[t] {
+			matches++
+		}
+	}
+
+	// Penalize length mismatch so "The Matrix" doesn't outscore an exact
+	// match against a much longer title that happens to share a word.
+	score := matches * 100 / len(bTokens)
+	if len(aTokens) != len(bTokens) {
+		score--
+	}
+	return score
+}
+
+func tokenize(s string) []string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
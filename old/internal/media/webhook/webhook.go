@@ -0,0 +1,270 @@
+// Package webhook exposes an HTTP receiver for Plex and Jellyfin scrobble
+// webhooks, forwarding play/pause/stop events to Trakt so watched state
+// stays in sync with whatever's actually playing on the media server.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/skamensky/skam.dev/internal/db"
+	"github.com/skamensky/skam.dev/internal/media/traktv"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Server handles Plex/Jellyfin webhook requests and forwards them to Trakt.
+// AuthToken must be non-empty and is compared (constant-time) against the
+// request's "token" query parameter before any event is processed; without
+// it, anyone who can reach this endpoint could forge a scrobble against the
+// user's real Trakt account. DiscordWebhookURL is optional; when set, every
+// event is also mirrored there.
+type Server struct {
+	db                *gorm.DB
+	trakt             *traktv.TraktItemList
+	DiscordWebhookURL string
+	AuthToken         string
+}
+
+func NewServer(gdb *gorm.DB, trakt *traktv.TraktItemList) *Server {
+	return &Server{db: gdb, trakt: trakt}
+}
+
+func (s *Server) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/webhook/plex", s.handlePlex)
+	mux.HandleFunc("/webhook/jellyfin", s.handleJellyfin)
+}
+
+// scrobbleEvent is the internal representation both payload formats are
+// normalized into before being forwarded to Trakt.
+type scrobbleEvent struct {
+	ImdbID   string
+	TmdbID   string
+	Title    string
+	Action   traktv.ScrobbleAction
+	Progress float64
+}
+
+var imdbGUIDRe = regexp.MustCompile(`imdb://(tt\d+)`)
+var tmdbGUIDRe = regexp.MustCompile(`tmdb://(\d+)`)
+
+func (s *Server) handlePlex(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(w, r) {
+		return
+	}
+	event, err := parsePlexPayload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.handleEvent(w, r.Context(), event)
+}
+
+func (s *Server) handleJellyfin(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(w, r) {
+		return
+	}
+	event, err := parseJellyfinPayload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.handleEvent(w, r.Context(), event)
+}
+
+// checkAuth compares r's "token" query parameter against s.AuthToken in
+// constant time, writing a 401 and returning false on mismatch. A query
+// parameter is used rather than a header since both Plex's and Jellyfin's
+// webhook settings only let you configure a destination URL, not custom
+// headers.
+func (s *Server) checkAuth(w http.ResponseWriter, r *http.Request) bool {
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(s.AuthToken)) != 1 {
+		log.Printf("webhook: rejected request from %s: bad or missing token", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleEvent(w http.ResponseWriter, ctx context.Context, event *scrobbleEvent) {
+	if event == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	entry, err := s.resolveEntry(event)
+	if err != nil {
+		log.Printf("webhook: failed to resolve media item for imdb=%s tmdb=%s: %v", event.ImdbID, event.TmdbID, err)
+		http.Error(w, "could not resolve media item", http.StatusNotFound)
+		return
+	}
+
+	if err := s.trakt.Scrobble(ctx, entry, event.Progress, event.Action); err != nil {
+		log.Printf("webhook: scrobble failed: %v", err)
+		http.Error(w, "failed to scrobble to trakt", http.StatusBadGateway)
+		return
+	}
+
+	s.notifyDiscord(event, entry)
+	w.WriteHeader(http.StatusOK)
+}
+
+// resolveEntry maps the GUID from the webhook payload to a MediaItem
+// already persisted by the trakt provider, matching against the
+// ids.imdb/ids.tmdb fields nested in MediaItem.Data (see TraktEntry.Ids).
+func (s *Server) resolveEntry(event *scrobbleEvent) (*traktv.TraktEntry, error) {
+	query := s.db.Model(&db.MediaItem{})
+	switch {
+	case event.ImdbID != "":
+		query = query.Where(datatypes.JSONQuery("data").Equals(event.ImdbID, "ids", "imdb"))
+	case event.TmdbID != "":
+		tmdbID, err := strconv.Atoi(event.TmdbID)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid tmdb id in webhook payload")
+		}
+		query = query.Where(datatypes.JSONQuery("data").Equals(tmdbID, "ids", "tmdb"))
+	default:
+		return nil, errors.New("webhook payload had no imdb or tmdb id")
+	}
+
+	var item db.MediaItem
+	if res := query.First(&item); res.Error != nil {
+		return nil, res.Error
+	}
+
+	var entry traktv.TraktEntry
+	if err := json.Unmarshal(item.Data, &entry); err != nil {
+		return nil, errors.Wrap(err, "failed to parse media item data")
+	}
+	return &entry, nil
+}
+
+func (s *Server) notifyDiscord(event *scrobbleEvent, entry *traktv.TraktEntry) {
+	if s.DiscordWebhookURL == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]string{
+		"content": string(event.Action) + ": " + entry.Title,
+	})
+	resp, err := http.Post(s.DiscordWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("webhook: discord fan-out failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// plexPayload models the fields we care about from Plex's webhook JSON,
+// which arrives as a multipart form field named "payload".
+type plexPayload struct {
+	Event    string `json:"event"`
+	Metadata struct {
+		Guid string `json:"guid"`
+		Title string `json:"title"`
+	} `json:"Metadata"`
+}
+
+func parsePlexPayload(r *http.Request) (*scrobbleEvent, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.Wrap(err, "expected multipart/form-data payload")
+	}
+
+	var payload plexPayload
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read multipart payload")
+		}
+		if part.FormName() == "payload" {
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read payload part")
+			}
+			if err := json.Unmarshal(data, &payload); err != nil {
+				return nil, errors.Wrap(err, "failed to parse payload json")
+			}
+		}
+	}
+
+	action, ok := plexAction(payload.Event)
+	if !ok {
+		// Events we don't care about (media.rate, library.new, etc).
+		return nil, nil
+	}
+
+	event := &scrobbleEvent{Title: payload.Metadata.Title, Action: action}
+	if m := imdbGUIDRe.FindStringSubmatch(payload.Metadata.Guid); m != nil {
+		event.ImdbID = m[1]
+	}
+	if m := tmdbGUIDRe.FindStringSubmatch(payload.Metadata.Guid); m != nil {
+		event.TmdbID = m[1]
+	}
+	return event, nil
+}
+
+func plexAction(event string) (traktv.ScrobbleAction, bool) {
+	switch event {
+	case "media.play", "media.resume":
+		return traktv.ScrobbleStart, true
+	case "media.pause":
+		return traktv.ScrobblePause, true
+	case "media.stop", "media.scrobble":
+		return traktv.ScrobbleStop, true
+	default:
+		return "", false
+	}
+}
+
+// jellyfinPayload models the fields the Jellyfin webhook plugin sends.
+type jellyfinPayload struct {
+	NotificationType string  `json:"NotificationType"`
+	ProviderImdb     string  `json:"Provider_imdb"`
+	ProviderTmdb     string  `json:"Provider_tmdb"`
+	Name             string  `json:"Name"`
+	PlaybackPosition float64 `json:"PlaybackPositionPercent"`
+}
+
+func parseJellyfinPayload(r *http.Request) (*scrobbleEvent, error) {
+	var payload jellyfinPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return nil, errors.Wrap(err, "failed to parse jellyfin payload")
+	}
+
+	action, ok := jellyfinAction(payload.NotificationType)
+	if !ok {
+		return nil, nil
+	}
+
+	return &scrobbleEvent{
+		ImdbID:   payload.ProviderImdb,
+		TmdbID:   payload.ProviderTmdb,
+		Title:    payload.Name,
+		Action:   action,
+		Progress: payload.PlaybackPosition,
+	}, nil
+}
+
+func jellyfinAction(notificationType string) (traktv.ScrobbleAction, bool) {
+	switch notificationType {
+	case "PlaybackStart", "PlaybackUnpause":
+		return traktv.ScrobbleStart, true
+	case "PlaybackPause":
+		return traktv.ScrobblePause, true
+	case "PlaybackStop":
+		return traktv.ScrobbleStop, true
+	default:
+		return "", false
+	}
+}
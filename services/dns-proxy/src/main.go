@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"encoding/xml"
@@ -8,10 +9,13 @@ import (
 	"io"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +27,33 @@ type DNSProxyRequest struct {
 	Data      map[string]string `json:"data"`
 	ProxyAuth string            `json:"proxy_auth"`
 	Sandbox   bool              `json:"sandbox"`
+
+	// WaitForPropagation, when true, makes present/addHost block after a
+	// successful Namecheap write until Expected is visible on every one of
+	// the domain's authoritative nameservers (or TimeoutSeconds elapses).
+	// setHosts ignores it and returns as soon as Namecheap answers. Namecheap's own propagation can take up to an hour, so ACME
+	// callers that return "success" before this check tends to fail the
+	// challenge that follows.
+	WaitForPropagation bool            `json:"wait_for_propagation,omitempty"`
+	TimeoutSeconds     int             `json:"timeout_seconds,omitempty"`
+	Expected           *ExpectedRecord `json:"expected,omitempty"`
+}
+
+// ExpectedRecord is the record waitForPropagation polls authoritative
+// nameservers for.
+type ExpectedRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// PropagationResult is one authoritative nameserver's outcome from
+// waitForPropagation, returned to the caller so it can see exactly which
+// server(s) lagged instead of just a single pass/fail bit.
+type PropagationResult struct {
+	Nameserver string `json:"nameserver"`
+	Propagated bool   `json:"propagated"`
+	Error      string `json:"error,omitempty"`
 }
 
 type DNSProxyResponse struct {
@@ -63,6 +94,18 @@ type NamecheapSetHostsResponse struct {
 	} `xml:"CommandResponse"`
 }
 
+type NamecheapTldListResponse struct {
+	NamecheapBaseResponse
+	CommandResponse struct {
+		Type string `xml:"Type,attr"`
+		Tlds struct {
+			Tld []struct {
+				Name string `xml:"Name,attr"`
+			} `xml:"Tld"`
+		} `xml:"Tlds"`
+	} `xml:"CommandResponse"`
+}
+
 type Host struct {
 	HostId     string `xml:"HostId,attr"`
 	Name       string `xml:"Name,attr"`
@@ -80,6 +123,7 @@ type HealthResponse struct {
 	Timestamp time.Time `json:"timestamp"`
 	Uptime    string    `json:"uptime"`
 	SupportedEnvironments []string `json:"supported_environments"`
+	EgressIP  string    `json:"egress_ip,omitempty"`
 }
 
 // ResponseWriter wrapper to capture status code and response size
@@ -189,11 +233,57 @@ func main() {
 		slog.String("log_level", logLevel.String()),
 	)
 
+	// Credentials dedicated to refreshing globalTldResolver's TLD list
+	// (namecheap.domains.getTldList), separate from the per-request
+	// credentials proxied through /api/dns. Optional: without them the
+	// proxy still works, just falling back to the naive SLD/TLD split.
+	tldAPIUser := os.Getenv("NAMECHEAP_API_USER")
+	tldAPIKey := os.Getenv("NAMECHEAP_API_KEY")
+	tldClientIP := os.Getenv("NAMECHEAP_CLIENT_IP")
+	if tldAPIUser != "" && tldAPIKey != "" && tldClientIP != "" {
+		if err := globalTldResolver.refresh("https://api.namecheap.com/xml.response", tldAPIUser, tldAPIKey, tldClientIP); err != nil {
+			slog.Error("initial TLD list refresh failed, falling back to naive SLD/TLD split", slog.String("error", err.Error()))
+		}
+		go func() {
+			ticker := time.NewTicker(24 * time.Hour)
+			defer ticker.Stop()
+			for range ticker.C {
+				if err := globalTldResolver.refresh("https://api.namecheap.com/xml.response", tldAPIUser, tldAPIKey, tldClientIP); err != nil {
+					slog.Error("periodic TLD list refresh failed", slog.String("error", err.Error()))
+				}
+			}
+		}()
+	} else {
+		slog.Warn("NAMECHEAP_API_USER/NAMECHEAP_API_KEY/NAMECHEAP_CLIENT_IP not set, SLD/TLD splitting will use the naive first-two-labels fallback")
+	}
+
+	// Detect our own egress IP so requests that leave client_ip empty or
+	// set to "auto" don't need the caller to know and whitelist it
+	// themselves on Namecheap.
+	publicIPEndpoint := os.Getenv("PUBLIC_IP_ENDPOINT")
+	if publicIPEndpoint == "" {
+		publicIPEndpoint = "https://api.ipify.org"
+	}
+	if err := globalEgressIP.refresh(publicIPEndpoint); err != nil {
+		slog.Error("initial egress IP detection failed, client_ip=auto will be rejected until it succeeds", slog.String("error", err.Error()))
+	}
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := globalEgressIP.refresh(publicIPEndpoint); err != nil {
+				slog.Error("periodic egress IP detection failed", slog.String("error", err.Error()))
+			}
+		}
+	}()
+
 	// Set up HTTP routes with logging middleware
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", homeHandler(serviceName))
 	mux.HandleFunc("/health", healthHandler(serviceName))
+	mux.HandleFunc("/whoami", whoamiHandler(proxyAuthToken))
 	mux.HandleFunc("/api/dns", dnsProxyHandler(proxyAuthToken))
+	mux.HandleFunc("/admin/refresh-tlds", refreshTldsHandler(proxyAuthToken, tldAPIUser, tldAPIKey, tldClientIP))
 
 	// Wrap the mux with logging middleware
 	httpHandler := loggingMiddleware(mux)
@@ -310,6 +400,7 @@ func healthHandler(serviceName string) http.HandlerFunc {
 			Timestamp:             time.Now(),
 			Uptime:                uptime.String(),
 			SupportedEnvironments: []string{"production", "sandbox"},
+			EgressIP:              globalEgressIP.current(),
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -318,6 +409,113 @@ func healthHandler(serviceName string) http.HandlerFunc {
 	}
 }
 
+// egressIPResolver caches the proxy's own outbound IP, detected by hitting
+// a STUN-style HTTP endpoint (e.g. https://api.ipify.org) that echoes back
+// whatever address it saw the request come from. Requests that leave
+// client_ip empty or set to the "auto" sentinel get this IP substituted in,
+// so callers don't each need to know and whitelist the proxy's own IP on
+// Namecheap.
+type egressIPResolver struct {
+	mu sync.RWMutex
+	ip string
+}
+
+func newEgressIPResolver() *egressIPResolver {
+	return &egressIPResolver{}
+}
+
+func (e *egressIPResolver) current() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.ip
+}
+
+func (e *egressIPResolver) refresh(endpoint string) error {
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to fetch egress IP: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read egress IP response: %w", err)
+	}
+
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("endpoint returned invalid IP: %q", ip)
+	}
+
+	e.mu.Lock()
+	e.ip = ip
+	e.mu.Unlock()
+
+	slog.Info("detected proxy egress IP", slog.String("ip", ip))
+	return nil
+}
+
+// globalEgressIP backs resolveClientIP. It's populated at startup (and
+// refreshed periodically) in main; until it's loaded, "auto"/empty
+// client_ip values have nothing to substitute and are rejected.
+var globalEgressIP = newEgressIPResolver()
+
+// resolveClientIP validates clientIP, rejecting anything net.ParseIP can't
+// parse, and substitutes the proxy's detected egress IP when clientIP is
+// empty or the "auto" sentinel.
+func resolveClientIP(clientIP string) (string, error) {
+	if clientIP == "" || clientIP == "auto" {
+		ip := globalEgressIP.current()
+		if ip == "" {
+			return "", fmt.Errorf("proxy egress IP not yet detected, specify client_ip explicitly")
+		}
+		return ip, nil
+	}
+	if net.ParseIP(clientIP) == nil {
+		return "", fmt.Errorf("invalid client_ip: %q", clientIP)
+	}
+	return clientIP, nil
+}
+
+// whoamiHandler reports the proxy's currently detected egress IP, so an
+// operator knows exactly which address to whitelist on Namecheap. Gated by
+// the same PROXY_AUTH_TOKEN as /api/dns, passed via X-Proxy-Auth since this
+// is a plain GET.
+func whoamiHandler(proxyAuthToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Proxy-Auth")), []byte(proxyAuthToken)) != 1 {
+			respondWithError(w, "Authentication failed", http.StatusUnauthorized)
+			return
+		}
+		respondWithSuccess(w, map[string]interface{}{"egress_ip": globalEgressIP.current()})
+	}
+}
+
+// refreshTldsHandler forces an immediate namecheap.domains.getTldList
+// refresh, gated by the same PROXY_AUTH_TOKEN as /api/dns (passed via the
+// X-Proxy-Auth header, since this is a plain GET with no JSON body).
+func refreshTldsHandler(proxyAuthToken, tldAPIUser, tldAPIKey, tldClientIP string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Proxy-Auth")), []byte(proxyAuthToken)) != 1 {
+			respondWithError(w, "Authentication failed", http.StatusUnauthorized)
+			return
+		}
+
+		if tldAPIUser == "" || tldAPIKey == "" || tldClientIP == "" {
+			respondWithError(w, "NAMECHEAP_API_USER/NAMECHEAP_API_KEY/NAMECHEAP_CLIENT_IP are not configured", http.StatusBadRequest)
+			return
+		}
+
+		if err := globalTldResolver.refresh("https://api.namecheap.com/xml.response", tldAPIUser, tldAPIKey, tldClientIP); err != nil {
+			slog.Error("manual TLD list refresh failed", slog.String("error", err.Error()))
+			respondWithError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		respondWithSuccess(w, map[string]interface{}{"refreshed": true})
+	}
+}
+
 func dnsProxyHandler(proxyAuthToken string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only accept POST requests
@@ -357,6 +555,21 @@ func dnsProxyHandler(proxyAuthToken string) http.HandlerFunc {
 			return
 		}
 
+		// Resolve client_ip once for every command: substitute our own
+		// egress IP for "" or "auto", and reject anything else that isn't
+		// a parseable IP before it reaches Namecheap.
+		clientIP, err := resolveClientIP(req.ClientIP)
+		if err != nil {
+			slog.Warn("invalid client_ip",
+				slog.String("client_ip", req.ClientIP),
+				slog.String("api_user", req.APIUser),
+				slog.String("error", err.Error()),
+			)
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.ClientIP = clientIP
+
 		// Determine API endpoint based on request
 		apiEndpoint := "https://api.namecheap.com/xml.response"
 		environment := "production"
@@ -381,6 +594,16 @@ func dnsProxyHandler(proxyAuthToken string) http.HandlerFunc {
 			handleGetHosts(w, req, apiEndpoint)
 		case "namecheap.domains.dns.setHosts":
 			handleSetHosts(w, req, apiEndpoint)
+		case "namecheap.domains.dns.present":
+			handlePresent(w, req, apiEndpoint)
+		case "namecheap.domains.dns.cleanup":
+			handleCleanup(w, req, apiEndpoint)
+		case "namecheap.domains.dns.addHost":
+			handleAddHost(w, req, apiEndpoint)
+		case "namecheap.domains.dns.removeHost":
+			handleRemoveHost(w, req, apiEndpoint)
+		case "namecheap.domains.dns.updateHost":
+			handleUpdateHost(w, req, apiEndpoint)
 		default:
 			slog.Warn("Unsupported command requested",
 				slog.String("command", req.Command),
@@ -406,22 +629,24 @@ func handleGetHosts(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint stri
 		return
 	}
 
-	// Split domain
-	parts := strings.Split(domain, ".")
-	if len(parts) < 2 {
+	// Split domain into SLD/TLD, validating the TLD against Namecheap's
+	// own list rather than naively assuming the last label is the TLD.
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
 		slog.Warn("getHosts request with invalid domain format",
 			slog.String("domain", domain),
 			slog.String("api_user", req.APIUser),
 			slog.String("client_ip", req.ClientIP),
+			slog.String("error", err.Error()),
 		)
-		respondWithError(w, "Invalid domain format", http.StatusBadRequest)
+		respondWithError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	slog.Info("Processing getHosts request",
 		slog.String("domain", domain),
-		slog.String("sld", parts[0]),
-		slog.String("tld", parts[1]),
+		slog.String("sld", sld),
+		slog.String("tld", tld),
 		slog.String("api_user", req.APIUser),
 		slog.String("client_ip", req.ClientIP),
 	)
@@ -433,8 +658,8 @@ func handleGetHosts(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint stri
 	params.Set("UserName", req.APIUser)
 	params.Set("Command", "namecheap.domains.dns.getHosts")
 	params.Set("ClientIp", req.ClientIP)
-	params.Set("SLD", parts[0])
-	params.Set("TLD", parts[1])
+	params.Set("SLD", sld)
+	params.Set("TLD", tld)
 
 	// Make API call
 	resp, err := http.Get(apiEndpoint + "?" + params.Encode())
@@ -543,15 +768,17 @@ func handleSetHosts(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint stri
 		return
 	}
 
-	// Split domain
-	parts := strings.Split(domain, ".")
-	if len(parts) < 2 {
+	// Split domain into SLD/TLD, validating the TLD against Namecheap's
+	// own list rather than naively assuming the last label is the TLD.
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
 		slog.Warn("setHosts request with invalid domain format",
 			slog.String("domain", domain),
 			slog.String("api_user", req.APIUser),
 			slog.String("client_ip", req.ClientIP),
+			slog.String("error", err.Error()),
 		)
-		respondWithError(w, "Invalid domain format", http.StatusBadRequest)
+		respondWithError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -562,8 +789,8 @@ func handleSetHosts(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint stri
 	params.Set("UserName", req.APIUser)
 	params.Set("Command", "namecheap.domains.dns.setHosts")
 	params.Set("ClientIp", req.ClientIP)
-	params.Set("SLD", parts[0])
-	params.Set("TLD", parts[1])
+	params.Set("SLD", sld)
+	params.Set("TLD", tld)
 
 	// Add host records
 	recordCount := 0
@@ -593,8 +820,8 @@ func handleSetHosts(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint stri
 
 	slog.Info("Processing setHosts request",
 		slog.String("domain", domain),
-		slog.String("sld", parts[0]),
-		slog.String("tld", parts[1]),
+		slog.String("sld", sld),
+		slog.String("tld", tld),
 		slog.String("api_user", req.APIUser),
 		slog.String("client_ip", req.ClientIP),
 		slog.Int("record_count", recordCount),
@@ -695,6 +922,747 @@ func handleSetHosts(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint stri
 	})
 }
 
+// defaultTTL is used for the ACME challenge TXT record when the caller
+// doesn't specify one; Namecheap's own minimum is 60 seconds but ACME
+// clients almost never care, so something short enough to clear the
+// previous challenge quickly is fine.
+const defaultTTL = "120"
+
+// namecheapGetHosts fetches the domain's full host record set, the first
+// half of every read-modify-write operation against Namecheap's
+// all-or-nothing setHosts API.
+func namecheapGetHosts(req DNSProxyRequest, apiEndpoint, sld, tld string) ([]Host, error) {
+	params := url.Values{}
+	params.Set("ApiUser", req.APIUser)
+	params.Set("ApiKey", req.APIKey)
+	params.Set("UserName", req.APIUser)
+	params.Set("Command", "namecheap.domains.dns.getHosts")
+	params.Set("ClientIp", req.ClientIP)
+	params.Set("SLD", sld)
+	params.Set("TLD", tld)
+
+	resp, err := http.Get(apiEndpoint + "?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var baseResp NamecheapBaseResponse
+	if err := xml.Unmarshal(body, &baseResp); err != nil {
+		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	}
+	if len(baseResp.Errors) > 0 {
+		return nil, fmt.Errorf("namecheap API error: %s", baseResp.Errors[0].Text)
+	}
+
+	var namecheapResp NamecheapGetHostsResponse
+	if err := xml.Unmarshal(body, &namecheapResp); err != nil {
+		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	}
+	return namecheapResp.CommandResponse.GetHostsResult.Hosts, nil
+}
+
+// namecheapSetHosts re-uploads the full host record set, the second half of
+// a read-modify-write operation. Namecheap has no per-record API, so every
+// existing record not being deliberately changed must be passed back
+// exactly as getHosts returned it or it's silently dropped from the zone.
+func namecheapSetHosts(req DNSProxyRequest, apiEndpoint, sld, tld string, hosts []Host) (bool, error) {
+	params := url.Values{}
+	params.Set("ApiUser", req.APIUser)
+	params.Set("ApiKey", req.APIKey)
+	params.Set("UserName", req.APIUser)
+	params.Set("Command", "namecheap.domains.dns.setHosts")
+	params.Set("ClientIp", req.ClientIP)
+	params.Set("SLD", sld)
+	params.Set("TLD", tld)
+
+	for i, host := range hosts {
+		n := strconv.Itoa(i + 1)
+		params.Set("HostName"+n, host.Name)
+		params.Set("RecordType"+n, host.Type)
+		params.Set("Address"+n, host.Address)
+		params.Set("TTL"+n, host.TTL)
+		if host.MXPref != "" {
+			params.Set("MXPref"+n, host.MXPref)
+		}
+		if host.IsActive != "" {
+			params.Set("IsActive"+n, host.IsActive)
+		}
+	}
+
+	resp, err := http.PostForm(apiEndpoint, params)
+	if err != nil {
+		return false, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var baseResp NamecheapBaseResponse
+	if err := xml.Unmarshal(body, &baseResp); err != nil {
+		return false, fmt.Errorf("failed to parse XML: %w", err)
+	}
+	if len(baseResp.Errors) > 0 {
+		return false, fmt.Errorf("namecheap API error: %s", baseResp.Errors[0].Text)
+	}
+
+	var namecheapResp NamecheapSetHostsResponse
+	if err := xml.Unmarshal(body, &namecheapResp); err != nil {
+		return false, fmt.Errorf("failed to parse XML: %w", err)
+	}
+	return namecheapResp.CommandResponse.SetHostsResult.IsSuccess == "true", nil
+}
+
+// domainLocks hands out one *sync.Mutex per domain so addHost/removeHost/
+// updateHost's getHosts-mutate-setHosts cycle runs atomically per domain:
+// two concurrent calls against the same domain serialize instead of racing
+// to overwrite each other's setHosts call and silently dropping a record.
+// Different domains still run fully in parallel.
+type domainLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newDomainLocks() *domainLocks {
+	return &domainLocks{locks: map[string]*sync.Mutex{}}
+}
+
+func (d *domainLocks) forDomain(domain string) *sync.Mutex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lock, ok := d.locks[domain]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.locks[domain] = lock
+	}
+	return lock
+}
+
+var globalDomainLocks = newDomainLocks()
+
+// splitDomainNaive is the original handleGetHosts/handleSetHosts SLD/TLD
+// split: wrong for multi-label TLDs (example.co.uk) and for any subdomain
+// input (www.example.com). resolveDomain below only falls back to it when
+// globalTldResolver hasn't loaded a TLD list to check against.
+func splitDomainNaive(domain string) (sld, tld string, ok bool) {
+	parts := strings.Split(domain, ".")
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// tldResolver caches Namecheap's TLD list (via namecheap.domains.getTldList)
+// in memory so splitDomain can correctly identify multi-label TLDs like
+// "co.uk" instead of assuming the last label is always the whole TLD.
+type tldResolver struct {
+	mu          sync.RWMutex
+	tlds        map[string]bool
+	lastRefresh time.Time
+}
+
+func newTldResolver() *tldResolver {
+	return &tldResolver{tlds: map[string]bool{}}
+}
+
+// loaded reports whether a TLD list has ever been successfully fetched.
+func (t *tldResolver) loaded() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.tlds) > 0
+}
+
+func (t *tldResolver) isKnown(tld string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tlds[strings.ToLower(tld)]
+}
+
+// refresh fetches the current TLD list from Namecheap and replaces the
+// cached set. Namecheap's TLD list is identical across accounts, so any
+// valid ApiUser/ApiKey/ClientIp works here; these are configured
+// separately from the per-request credentials proxied through /api/dns
+// (see NAMECHEAP_API_USER/NAMECHEAP_API_KEY/NAMECHEAP_CLIENT_IP in main).
+func (t *tldResolver) refresh(apiEndpoint, apiUser, apiKey, clientIP string) error {
+	params := url.Values{}
+	params.Set("ApiUser", apiUser)
+	params.Set("ApiKey", apiKey)
+	params.Set("UserName", apiUser)
+	params.Set("Command", "namecheap.domains.getTldList")
+	params.Set("ClientIp", clientIP)
+
+	resp, err := http.Get(apiEndpoint + "?" + params.Encode())
+	if err != nil {
+		return fmt.Errorf("getTldList request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read getTldList response: %w", err)
+	}
+
+	var baseResp NamecheapBaseResponse
+	if err := xml.Unmarshal(body, &baseResp); err != nil {
+		return fmt.Errorf("failed to parse getTldList XML: %w", err)
+	}
+	if len(baseResp.Errors) > 0 {
+		return fmt.Errorf("namecheap API error: %s", baseResp.Errors[0].Text)
+	}
+
+	var tldResp NamecheapTldListResponse
+	if err := xml.Unmarshal(body, &tldResp); err != nil {
+		return fmt.Errorf("failed to parse getTldList XML: %w", err)
+	}
+
+	tlds := make(map[string]bool, len(tldResp.CommandResponse.Tlds.Tld))
+	for _, tld := range tldResp.CommandResponse.Tlds.Tld {
+		tlds[strings.ToLower(tld.Name)] = true
+	}
+
+	t.mu.Lock()
+	t.tlds = tlds
+	t.lastRefresh = time.Now()
+	t.mu.Unlock()
+
+	slog.Info("refreshed namecheap TLD list", slog.Int("tld_count", len(tlds)))
+	return nil
+}
+
+// globalTldResolver backs resolveDomain. It's populated at startup (and
+// refreshed daily) in main if NAMECHEAP_API_USER/NAMECHEAP_API_KEY/
+// NAMECHEAP_CLIENT_IP are configured; until it's loaded, resolveDomain degrades to
+// splitDomainNaive.
+var globalTldResolver = newTldResolver()
+
+// splitDomain walks fqdn's label suffixes longest-first, matching each
+// against the resolver's known-TLD set, and returns the first (therefore
+// longest) match as (sld, tld, subdomain). This correctly handles
+// multi-label TLDs like "co.uk" and subdomains like "www.example.com",
+// unlike splitDomainNaive's assumption that the TLD is always one label.
+func splitDomain(fqdn string, resolver *tldResolver) (sld, tld, sub string, err error) {
+	fqdn = strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	labels := strings.Split(fqdn, ".")
+	if len(labels) < 2 {
+		return "", "", "", fmt.Errorf("invalid domain format: %q", fqdn)
+	}
+
+	for tldLabels := len(labels) - 1; tldLabels >= 1; tldLabels-- {
+		sldIdx := len(labels) - tldLabels - 1
+		if sldIdx < 0 {
+			continue
+		}
+		candidate := strings.Join(labels[sldIdx+1:], ".")
+		if resolver.isKnown(candidate) {
+			return labels[sldIdx], candidate, strings.Join(labels[:sldIdx], "."), nil
+		}
+	}
+	return "", "", "", fmt.Errorf("%q has no recognized TLD", fqdn)
+}
+
+// resolveDomain is the single entry point handlers use to split a domain
+// into SLD/TLD: it validates against globalTldResolver's TLD list when
+// one has been loaded, and falls back to splitDomainNaive's first-two-labels
+// split (logging a warning) when it hasn't, so the proxy still works before its
+// first successful getTldList call.
+func resolveDomain(domain string) (sld, tld, sub string, err error) {
+	if globalTldResolver.loaded() {
+		return splitDomain(domain, globalTldResolver)
+	}
+	slog.Warn("TLD list not loaded, falling back to naive SLD/TLD split", slog.String("domain", domain))
+	s, t, ok := splitDomainNaive(domain)
+	if !ok {
+		return "", "", "", fmt.Errorf("invalid domain format")
+	}
+	return s, t, "", nil
+}
+
+// acmeRecordName returns the subdomain-relative name ACME's _acme-challenge
+// TXT record should be published under, e.g. "_acme-challenge" for
+// fqdn=_acme-challenge.example.com, domain=example.com, or
+// "_acme-challenge.sub" for fqdn=_acme-challenge.sub.example.com.
+func acmeRecordName(fqdn, domain string) string {
+	fqdn = strings.TrimSuffix(fqdn, ".")
+	return strings.TrimSuffix(fqdn, "."+domain)
+}
+
+// queryNS asks nameserver directly (bypassing any recursive resolver cache)
+// for fqdn's recordType record and returns the first answer's value, or ""
+// if there's no answer yet. recordType is one of "TXT", "A", or "CNAME".
+func queryNS(nameserver, fqdn, recordType string) (string, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: 5 * time.Second}
+			return d.DialContext(ctx, network, net.JoinHostPort(nameserver, "53"))
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	switch recordType {
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, fqdn)
+		if err != nil || len(txts) == 0 {
+			return "", err
+		}
+		return txts[0], nil
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, fqdn)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(cname, "."), nil
+	default:
+		ips, err := resolver.LookupHost(ctx, fqdn)
+		if err != nil || len(ips) == 0 {
+			return "", err
+		}
+		return ips[0], nil
+	}
+}
+
+// pollNameserver retries queryNS against one nameserver with exponential
+// backoff (starting at 2s, capped at 30s) until it sees expectedValue or
+// deadline passes.
+func pollNameserver(nameserver, fqdn, recordType, expectedValue string, deadline time.Time) PropagationResult {
+	const maxBackoff = 30 * time.Second
+	backoff := 2 * time.Second
+	var lastErr error
+
+	for {
+		value, err := queryNS(nameserver, fqdn, recordType)
+		if err != nil {
+			lastErr = err
+		} else if value == expectedValue {
+			return PropagationResult{Nameserver: nameserver, Propagated: true}
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+
+	result := PropagationResult{Nameserver: nameserver, Propagated: false}
+	if lastErr != nil {
+		result.Error = lastErr.Error()
+	}
+	return result
+}
+
+// waitForPropagation resolves domain's authoritative nameservers and polls
+// every one of them in parallel until each sees expected's value or timeout
+// expires, returning one PropagationResult per nameserver.
+func waitForPropagation(domain string, expected ExpectedRecord, timeout time.Duration) []PropagationResult {
+	nss, err := net.LookupNS(domain)
+	if err != nil || len(nss) == 0 {
+		return []PropagationResult{{Nameserver: domain, Propagated: false, Error: fmt.Sprintf("failed to resolve NS records: %v", err)}}
+	}
+
+	recordType := strings.ToUpper(expected.Type)
+	switch recordType {
+	case "TXT", "A", "CNAME":
+	default:
+		return []PropagationResult{{Propagated: false, Error: fmt.Sprintf("unsupported record type %q", expected.Type)}}
+	}
+
+	fqdn := expected.Name
+	if !strings.HasSuffix(strings.TrimSuffix(fqdn, "."), domain) {
+		fqdn = expected.Name + "." + domain
+	}
+
+	deadline := time.Now().Add(timeout)
+	results := make([]PropagationResult, len(nss))
+	var wg sync.WaitGroup
+	for i, ns := range nss {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			results[i] = pollNameserver(strings.TrimSuffix(host, "."), fqdn, recordType, expected.Value, deadline)
+		}(i, ns.Host)
+	}
+	wg.Wait()
+	return results
+}
+
+// maybeWaitForPropagation runs waitForPropagation when the request opted in
+// and the write succeeded; otherwise it returns nil so callers can skip
+// adding a "propagation" key to their response.
+func maybeWaitForPropagation(req DNSProxyRequest, domain string, writeSucceeded bool) []PropagationResult {
+	if !writeSucceeded || !req.WaitForPropagation || req.Expected == nil {
+		return nil
+	}
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return waitForPropagation(domain, *req.Expected, timeout)
+}
+
+func handlePresent(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint string) {
+	start := time.Now()
+
+	domain := req.Data["domain"]
+	fqdn := req.Data["fqdn"]
+	value := req.Data["value"]
+	if domain == "" || fqdn == "" || value == "" {
+		respondWithError(w, "domain, fqdn, and value parameters are required", http.StatusBadRequest)
+		return
+	}
+	ttl := req.Data["ttl"]
+	if ttl == "" {
+		ttl = defaultTTL
+	}
+
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := acmeRecordName(fqdn, domain)
+
+	lock := globalDomainLocks.forDomain(domain)
+	lock.Lock()
+	defer lock.Unlock()
+
+	hosts, err := namecheapGetHosts(req, apiEndpoint, sld, tld)
+	if err != nil {
+		slog.Error("present: failed to fetch existing hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hosts = append(hosts, Host{Name: name, Type: "TXT", Address: value, TTL: ttl})
+
+	success, err := namecheapSetHosts(req, apiEndpoint, sld, tld, hosts)
+	if err != nil {
+		slog.Error("present: failed to set hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	propagation := maybeWaitForPropagation(req, domain, success)
+
+	slog.Info("present request completed",
+		slog.String("domain", domain),
+		slog.String("fqdn", fqdn),
+		slog.Int("record_count", len(hosts)),
+		slog.Bool("success", success),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	data := map[string]interface{}{
+		"success":     success,
+		"recordCount": len(hosts),
+		"addedRecord": name,
+	}
+	if propagation != nil {
+		data["propagation"] = propagation
+	}
+	respondWithSuccess(w, data)
+}
+
+func handleCleanup(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint string) {
+	start := time.Now()
+
+	domain := req.Data["domain"]
+	fqdn := req.Data["fqdn"]
+	value := req.Data["value"]
+	if domain == "" || fqdn == "" || value == "" {
+		respondWithError(w, "domain, fqdn, and value parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := acmeRecordName(fqdn, domain)
+
+	lock := globalDomainLocks.forDomain(domain)
+	lock.Lock()
+	defer lock.Unlock()
+
+	hosts, err := namecheapGetHosts(req, apiEndpoint, sld, tld)
+	if err != nil {
+		slog.Error("cleanup: failed to fetch existing hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	remaining := hosts[:0]
+	for _, host := range hosts {
+		if host.Type == "TXT" && host.Name == name && host.Address == value {
+			continue
+		}
+		remaining = append(remaining, host)
+	}
+
+	success, err := namecheapSetHosts(req, apiEndpoint, sld, tld, remaining)
+	if err != nil {
+		slog.Error("cleanup: failed to set hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("cleanup request completed",
+		slog.String("domain", domain),
+		slog.String("fqdn", fqdn),
+		slog.Int("record_count", len(remaining)),
+		slog.Bool("success", success),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	respondWithSuccess(w, map[string]interface{}{
+		"success":       success,
+		"recordCount":   len(remaining),
+		"removedRecord": name,
+	})
+}
+
+// handleAddHost appends a single DNS record via getHosts -> append ->
+// setHosts, rejecting an exact (name, type, address) duplicate rather than
+// silently doubling it up.
+func handleAddHost(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint string) {
+	start := time.Now()
+
+	domain := req.Data["domain"]
+	name := req.Data["name"]
+	hostType := req.Data["type"]
+	address := req.Data["address"]
+	if domain == "" || name == "" || hostType == "" || address == "" {
+		respondWithError(w, "domain, name, type, and address parameters are required", http.StatusBadRequest)
+		return
+	}
+	ttl := req.Data["ttl"]
+	if ttl == "" {
+		ttl = defaultTTL
+	}
+
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lock := globalDomainLocks.forDomain(domain)
+	lock.Lock()
+	defer lock.Unlock()
+
+	hosts, err := namecheapGetHosts(req, apiEndpoint, sld, tld)
+	if err != nil {
+		slog.Error("addHost: failed to fetch existing hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	before := len(hosts)
+
+	for _, host := range hosts {
+		if host.Name == name && host.Type == hostType && host.Address == address {
+			respondWithError(w, "record already exists", http.StatusConflict)
+			return
+		}
+	}
+
+	hosts = append(hosts, Host{Name: name, Type: hostType, Address: address, TTL: ttl, MXPref: req.Data["mxpref"], IsActive: "true"})
+
+	success, err := namecheapSetHosts(req, apiEndpoint, sld, tld, hosts)
+	if err != nil {
+		slog.Error("addHost: failed to set hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	propagation := maybeWaitForPropagation(req, domain, success)
+
+	slog.Info("addHost request completed",
+		slog.String("domain", domain),
+		slog.String("name", name),
+		slog.Int("before_count", before),
+		slog.Int("after_count", len(hosts)),
+		slog.Bool("success", success),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	data := map[string]interface{}{
+		"success":     success,
+		"beforeCount": before,
+		"afterCount":  len(hosts),
+	}
+	if propagation != nil {
+		data["propagation"] = propagation
+	}
+	respondWithSuccess(w, data)
+}
+
+// handleRemoveHost drops every record matching (name, type) and, if
+// address is given, also address, via getHosts -> filter -> setHosts.
+func handleRemoveHost(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint string) {
+	start := time.Now()
+
+	domain := req.Data["domain"]
+	name := req.Data["name"]
+	hostType := req.Data["type"]
+	address := req.Data["address"]
+	if domain == "" || name == "" || hostType == "" {
+		respondWithError(w, "domain, name, and type parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lock := globalDomainLocks.forDomain(domain)
+	lock.Lock()
+	defer lock.Unlock()
+
+	hosts, err := namecheapGetHosts(req, apiEndpoint, sld, tld)
+	if err != nil {
+		slog.Error("removeHost: failed to fetch existing hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	before := len(hosts)
+
+	remaining := hosts[:0]
+	for _, host := range hosts {
+		if host.Name == name && host.Type == hostType && (address == "" || host.Address == address) {
+			continue
+		}
+		remaining = append(remaining, host)
+	}
+
+	success, err := namecheapSetHosts(req, apiEndpoint, sld, tld, remaining)
+	if err != nil {
+		slog.Error("removeHost: failed to set hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("removeHost request completed",
+		slog.String("domain", domain),
+		slog.String("name", name),
+		slog.Int("before_count", before),
+		slog.Int("after_count", len(remaining)),
+		slog.Bool("success", success),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	respondWithSuccess(w, map[string]interface{}{
+		"success":     success,
+		"beforeCount": before,
+		"afterCount":  len(remaining),
+	})
+}
+
+// handleUpdateHost mutates every record matching match_name/match_type
+// (and, if given, match_address) in place, applying whichever set_* fields
+// were provided, then writes the whole list back with setHosts. Unmatched
+// records, and unset fields on matched ones, pass through unchanged.
+func handleUpdateHost(w http.ResponseWriter, req DNSProxyRequest, apiEndpoint string) {
+	start := time.Now()
+
+	domain := req.Data["domain"]
+	matchName := req.Data["match_name"]
+	matchType := req.Data["match_type"]
+	matchAddress := req.Data["match_address"]
+	if domain == "" || matchName == "" || matchType == "" {
+		respondWithError(w, "domain, match_name, and match_type parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	sld, tld, _, err := resolveDomain(domain)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lock := globalDomainLocks.forDomain(domain)
+	lock.Lock()
+	defer lock.Unlock()
+
+	hosts, err := namecheapGetHosts(req, apiEndpoint, sld, tld)
+	if err != nil {
+		slog.Error("updateHost: failed to fetch existing hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	matched := 0
+	for i, host := range hosts {
+		if host.Name != matchName || host.Type != matchType {
+			continue
+		}
+		if matchAddress != "" && host.Address != matchAddress {
+			continue
+		}
+		matched++
+		if v, ok := req.Data["set_name"]; ok {
+			hosts[i].Name = v
+		}
+		if v, ok := req.Data["set_type"]; ok {
+			hosts[i].Type = v
+		}
+		if v, ok := req.Data["set_address"]; ok {
+			hosts[i].Address = v
+		}
+		if v, ok := req.Data["set_ttl"]; ok {
+			hosts[i].TTL = v
+		}
+		if v, ok := req.Data["set_mxpref"]; ok {
+			hosts[i].MXPref = v
+		}
+	}
+
+	if matched == 0 {
+		respondWithError(w, "no matching record found", http.StatusNotFound)
+		return
+	}
+
+	success, err := namecheapSetHosts(req, apiEndpoint, sld, tld, hosts)
+	if err != nil {
+		slog.Error("updateHost: failed to set hosts", slog.String("error", err.Error()), slog.String("domain", domain))
+		respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("updateHost request completed",
+		slog.String("domain", domain),
+		slog.String("match_name", matchName),
+		slog.Int("matched_count", matched),
+		slog.Bool("success", success),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	respondWithSuccess(w, map[string]interface{}{
+		"success":      success,
+		"beforeCount":  len(hosts),
+		"afterCount":   len(hosts),
+		"matchedCount": matched,
+	})
+}
+
 func respondWithError(w http.ResponseWriter, message string, statusCode int) {
 	response := DNSProxyResponse{
 		Success: false,
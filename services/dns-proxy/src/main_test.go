@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+func resolverWithTlds(tlds ...string) *tldResolver {
+	r := newTldResolver()
+	for _, tld := range tlds {
+		r.tlds[tld] = true
+	}
+	return r
+}
+
+func TestSplitDomain(t *testing.T) {
+	cases := []struct {
+		name    string
+		fqdn    string
+		tlds    []string
+		wantSld string
+		wantTld string
+		wantSub string
+		wantErr bool
+	}{
+		{
+			name:    "bare SLD.TLD",
+			fqdn:    "example.com",
+			tlds:    []string{"com"},
+			wantSld: "example",
+			wantTld: "com",
+			wantSub: "",
+		},
+		{
+			name:    "subdomain",
+			fqdn:    "www.example.com",
+			tlds:    []string{"com"},
+			wantSld: "example",
+			wantTld: "com",
+			wantSub: "www",
+		},
+		{
+			name:    "multi-label public suffix",
+			fqdn:    "example.co.uk",
+			tlds:    []string{"co.uk", "uk"},
+			wantSld: "example",
+			wantTld: "co.uk",
+			wantSub: "",
+		},
+		{
+			name:    "subdomain under a multi-label public suffix",
+			fqdn:    "_acme-challenge.www.example.co.uk",
+			tlds:    []string{"co.uk", "uk"},
+			wantSld: "example",
+			wantTld: "co.uk",
+			wantSub: "_acme-challenge.www",
+		},
+		{
+			name:    "trailing dot is trimmed",
+			fqdn:    "example.com.",
+			tlds:    []string{"com"},
+			wantSld: "example",
+			wantTld: "com",
+			wantSub: "",
+		},
+		{
+			name:    "mixed case is lowercased",
+			fqdn:    "WWW.Example.COM",
+			tlds:    []string{"com"},
+			wantSld: "example",
+			wantTld: "com",
+			wantSub: "www",
+		},
+		{
+			name:    "unrecognized TLD",
+			fqdn:    "example.zzz",
+			tlds:    []string{"com"},
+			wantErr: true,
+		},
+		{
+			name:    "bare SLD with no dot at all",
+			fqdn:    "localhost",
+			tlds:    []string{"com"},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resolver := resolverWithTlds(c.tlds...)
+			sld, tld, sub, err := splitDomain(c.fqdn, resolver)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("splitDomain(%q) = (%q, %q, %q), want an error", c.fqdn, sld, tld, sub)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitDomain(%q) returned unexpected error: %v", c.fqdn, err)
+			}
+			if sld != c.wantSld || tld != c.wantTld || sub != c.wantSub {
+				t.Fatalf("splitDomain(%q) = (%q, %q, %q), want (%q, %q, %q)",
+					c.fqdn, sld, tld, sub, c.wantSld, c.wantTld, c.wantSub)
+			}
+		})
+	}
+}
+
+func TestSplitDomainPrefersLongestKnownSuffix(t *testing.T) {
+	// "co.uk" and "uk" are both known; the longer match must win so
+	// "example" (not "example.co") ends up as the SLD.
+	resolver := resolverWithTlds("uk", "co.uk")
+	sld, tld, sub, err := splitDomain("example.co.uk", resolver)
+	if err != nil {
+		t.Fatalf("splitDomain returned unexpected error: %v", err)
+	}
+	if sld != "example" || tld != "co.uk" || sub != "" {
+		t.Fatalf("splitDomain = (%q, %q, %q), want (\"example\", \"co.uk\", \"\")", sld, tld, sub)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+// Wrap and its helpers give homeHandler/healthHandler/statusHandler a
+// shared, typed shape instead of each hand-rolling
+// w.Header().Set/WriteHeader/json.NewEncoder(w).Encode. It would be its
+// own "api" package in a multi-module layout, but this service still
+// ships as a single unmoduled binary, so it lives here as package main
+// instead.
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// APIError is a typed handler error. Wrap renders it with Status (defaulting
+// to 500 for a plain error) instead of a handler writing the status code
+// itself.
+type APIError struct {
+	Status  int
+	Message string
+	Details interface{}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// envelope is the JSON shape every Wrap-rendered response takes, success or
+// failure: Message/Details on success, Error (and optionally Details) on
+// failure.
+type envelope struct {
+	Message string      `json:"message,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Renderer renders a successful *Resp for one negotiated content type.
+type Renderer[Resp any] func(w http.ResponseWriter, resp *Resp)
+
+// JSONRenderer renders resp as the standard envelope, with resp itself as
+// Details and message as the top-level Message.
+func JSONRenderer[Resp any](message string) Renderer[Resp] {
+	return func(w http.ResponseWriter, resp *Resp) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(envelope{Message: message, Details: resp})
+	}
+}
+
+// TemplateRenderer renders resp through tmpl, for handlers that serve HTML
+// to a browser rather than JSON to an API client.
+func TemplateRenderer[Resp any](tmpl *template.Template) Renderer[Resp] {
+	return func(w http.ResponseWriter, resp *Resp) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		tmpl.Execute(w, resp)
+	}
+}
+
+// Wrap adapts a typed handler into a plain http.HandlerFunc. On success it
+// renders via htmlRenderer when the request's Accept header prefers
+// text/html over application/json (a browser navigation, typically), and
+// via jsonRenderer otherwise; htmlRenderer may be nil for endpoints with no
+// HTML representation. On error it renders the standard envelope, using
+// *APIError's Status if the handler returned one, or 500 otherwise.
+func Wrap[Req any, Resp any](handler func(r *http.Request, req *Req) (*Resp, error), jsonRenderer, htmlRenderer Renderer[Resp]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if (r.Method == http.MethodPost || r.Method == http.MethodPut) && r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, &APIError{Status: http.StatusBadRequest, Message: "invalid request body", Details: err.Error()})
+				return
+			}
+		}
+
+		resp, err := handler(r, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if htmlRenderer != nil && wantsHTML(r) {
+			htmlRenderer(w, resp)
+			return
+		}
+		jsonRenderer(w, resp)
+	}
+}
+
+// wantsHTML reports whether Accept names text/html ahead of
+// application/json, the signal a browser navigation sends that an API
+// client normally doesn't.
+func wantsHTML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	htmlIdx := strings.Index(accept, "text/html")
+	if htmlIdx == -1 {
+		return false
+	}
+	jsonIdx := strings.Index(accept, "application/json")
+	return jsonIdx == -1 || htmlIdx < jsonIdx
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "internal error"
+	var details interface{}
+	if apiErr, ok := err.(*APIError); ok {
+		if apiErr.Status != 0 {
+			status = apiErr.Status
+		}
+		message = apiErr.Message
+		details = apiErr.Details
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope{Error: message, Details: details})
+}
@@ -0,0 +1,57 @@
+package main
+
+// Static markup, CSS, and templates live under web/ and are compiled into
+// the binary via go:embed, rather than as Go string literals, so a
+// downstream deployment can override an individual template or asset file
+// without touching this package.
+
+import (
+	"embed"
+	"net/http"
+	"path"
+	"strings"
+)
+
+//go:embed web/templates/*.html
+var templateFS embed.FS
+
+//go:embed web/static
+var staticFS embed.FS
+
+// staticContentTypes maps a served file's extension to its Content-Type,
+// since this is plain embed.FS with no net/http.FileServer-style sniffing.
+var staticContentTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".js":   "application/javascript; charset=utf-8",
+	".png":  "image/png",
+	".ico":  "image/x-icon",
+}
+
+// assetsHandler serves everything embedded under web/static at /static/,
+// content-type-sniffed by file extension, with a 404 fallback for unknown
+// paths and a Cache-Control header since embedded assets only change on
+// redeploy.
+func assetsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/static/")
+		if name == "" || strings.Contains(name, "..") {
+			http.NotFound(w, r)
+			return
+		}
+
+		data, err := staticFS.ReadFile(path.Join("web/static", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		contentType, ok := staticContentTypes[strings.ToLower(path.Ext(name))]
+		if !ok {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(data)
+	}
+}
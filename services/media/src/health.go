@@ -0,0 +1,243 @@
+package main
+
+// health is a small pluggable health-check subsystem: named CheckFuncs are
+// registered up front, run on a repeating background ticker, and cached so
+// /readyz stays O(1) under load instead of re-probing every dependency on
+// every request.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckFunc is one named health probe; it should respect ctx's deadline and
+// return a non-nil error on failure.
+type CheckFunc func(ctx context.Context) error
+
+// checkResult is a CheckFunc's cached outcome, refreshed on every tick.
+type checkResult struct {
+	OK          bool      `json:"ok"`
+	LatencyMS   int64     `json:"latency_ms"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+}
+
+// healthRegistry runs every registered CheckFunc on a repeating ticker and
+// caches each one's result.
+type healthRegistry struct {
+	mu      sync.RWMutex
+	checks  map[string]CheckFunc
+	results map[string]checkResult
+}
+
+func newHealthRegistry() *healthRegistry {
+	return &healthRegistry{
+		checks:  map[string]CheckFunc{},
+		results: map[string]checkResult{},
+	}
+}
+
+// Register adds a named check. Call it before Run starts the background
+// ticker; registering after Run has started is not safe for concurrent use.
+func (h *healthRegistry) Register(name string, fn CheckFunc) {
+	h.checks[name] = fn
+}
+
+// Run evaluates every registered check once immediately, then again every
+// interval, until ctx is cancelled.
+func (h *healthRegistry) Run(ctx context.Context, interval time.Duration) {
+	h.runOnce(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.runOnce(ctx)
+		}
+	}
+}
+
+func (h *healthRegistry) runOnce(ctx context.Context) {
+	h.mu.RLock()
+	checks := make(map[string]CheckFunc, len(h.checks))
+	for name, fn := range h.checks {
+		checks[name] = fn
+	}
+	h.mu.RUnlock()
+
+	for name, fn := range checks {
+		start := time.Now()
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := fn(checkCtx)
+		cancel()
+
+		result := checkResult{OK: err == nil, LatencyMS: time.Since(start).Milliseconds(), LastChecked: time.Now()}
+		if err != nil {
+			result.LastError = err.Error()
+		}
+
+		h.mu.Lock()
+		h.results[name] = result
+		h.mu.Unlock()
+	}
+}
+
+// ready reports whether every registered check's cached result currently
+// passes. A check that hasn't run yet counts as not ready.
+func (h *healthRegistry) ready() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.results) < len(h.checks) {
+		return false
+	}
+	for _, result := range h.results {
+		if !result.OK {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *healthRegistry) snapshot() map[string]checkResult {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make(map[string]checkResult, len(h.results))
+	for name, result := range h.results {
+		out[name] = result
+	}
+	return out
+}
+
+// HTTPCheck probes url with a GET, passing if the response status is below
+// 400.
+func HTTPCheck(url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// TCPCheck probes addr ("host:port") with a plain dial.
+func TCPCheck(addr string) CheckFunc {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// registerChecksFromEnv wires up HTTP/TCP probes named in HEALTH_HTTP_CHECKS
+// and HEALTH_TCP_CHECKS, each a comma-separated list of name=target pairs,
+// e.g. HEALTH_HTTP_CHECKS="tmdb=https://api.themoviedb.org/3,trakt=https://api.trakt.tv".
+func registerChecksFromEnv(registry *healthRegistry) {
+	for name, url := range parseNameValuePairs(os.Getenv("HEALTH_HTTP_CHECKS")) {
+		registry.Register(name, HTTPCheck(url))
+	}
+	for name, addr := range parseNameValuePairs(os.Getenv("HEALTH_TCP_CHECKS")) {
+		registry.Register(name, TCPCheck(addr))
+	}
+}
+
+func parseNameValuePairs(s string) map[string]string {
+	pairs := map[string]string{}
+	if s == "" {
+		return pairs
+	}
+	for _, entry := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		pairs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return pairs
+}
+
+// livezHandler reports only that the process is up; unlike /readyz it
+// never consults registered checks.
+func livezHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
+
+// readyzHandler reports 200 when every registered check's cached result
+// currently passes, 503 otherwise, for a Kubernetes/Traefik readiness probe
+// to act on.
+func readyzHandler(registry *healthRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if registry.ready() {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("ok"))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not ready"))
+	}
+}
+
+// detailedHealthResponse is /health's JSON report: the previous
+// status/service/uptime fields plus a per-check breakdown.
+type detailedHealthResponse struct {
+	Status    string                 `json:"status"`
+	Service   string                 `json:"service"`
+	Timestamp time.Time              `json:"timestamp"`
+	Uptime    string                 `json:"uptime"`
+	Checks    map[string]checkResult `json:"checks,omitempty"`
+}
+
+// healthDetailRenderer is healthHandler's JSON renderer: unlike
+// JSONRenderer it picks the HTTP status itself, 503 when resp reports
+// anything but "healthy".
+func healthDetailRenderer(w http.ResponseWriter, resp *detailedHealthResponse) {
+	status := http.StatusOK
+	if resp.Status != "healthy" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope{Message: "ok", Details: resp})
+}
+
+// healthHandler reports a detailed per-check breakdown, flipping to 503 if
+// any registered check is currently failing.
+func healthHandler(serviceName string, registry *healthRegistry) http.HandlerFunc {
+	type emptyRequest struct{}
+	return Wrap(func(r *http.Request, _ *emptyRequest) (*detailedHealthResponse, error) {
+		status := "healthy"
+		if !registry.ready() {
+			status = "degraded"
+		}
+		return &detailedHealthResponse{
+			Status:    status,
+			Service:   serviceName,
+			Timestamp: time.Now(),
+			Uptime:    time.Since(startTime).String(),
+			Checks:    registry.snapshot(),
+		}, nil
+	}, healthDetailRenderer, nil)
+}
@@ -1,11 +1,26 @@
 package main
 
+// This service intentionally exposes HTTP only, not gRPC. A gRPC transport
+// was tried once, but it pulled in google.golang.org/grpc and a generated
+// proto package with nothing in this tree (or services/dns-proxy's) to
+// resolve them against, since neither has a go.mod — the same
+// single-unmoduled-binary constraint the comment at the top of
+// apihandler.go describes. Revisit this as a real Go module with committed generated
+// stubs if gRPC is needed again; don't reintroduce the import without one.
+
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
+	"html/template"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -16,16 +31,203 @@ type Response struct {
 	Status    string    `json:"status"`
 }
 
-type HealthResponse struct {
-	Status    string    `json:"status"`
-	Service   string    `json:"service"`
-	Timestamp time.Time `json:"timestamp"`
-	Uptime    string    `json:"uptime"`
+// HomeResponse is homeHandler's typed result: the same data rendered as
+// either the JSON envelope's details or the homepage's HTML, depending on
+// what Wrap negotiates.
+type HomeResponse struct {
+	Service      string    `json:"service"`
+	Timestamp    time.Time `json:"timestamp"`
+	BuildVersion string    `json:"build_version"`
 }
 
 var startTime = time.Now()
 
+// buildVersion is set via -ldflags "-X main.buildVersion=..." at build
+// time; it defaults to "dev" for local/unversioned builds.
+var buildVersion = "dev"
+
+// statusWriter wraps http.ResponseWriter to capture the status code and
+// response size a handler writes, so metricsMiddleware and loggingMiddleware
+// can label their observations with them after the handler returns.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+	size       int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.statusCode = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	n, err := sw.ResponseWriter.Write(b)
+	sw.size += n
+	return n, err
+}
+
+// defaultLatencyBuckets are Prometheus's own client library defaults,
+// reused here since there's no reason to pick different boundaries.
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram is a cumulative Prometheus-style histogram: counts[i] is the
+// number of observations <= buckets[i].
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(seconds float64) {
+	for i, bound := range h.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+type requestKey struct {
+	method string
+	path   string
+	status string
+}
+
+type routeKey struct {
+	method string
+	path   string
+}
+
+// metricsRegistry is a minimal in-process Prometheus-style registry: just
+// enough to track per-route request counts and latency histograms and
+// render them in the standard text exposition format, without pulling in
+// the full client_golang dependency for a handful of boilerplate handlers.
+type metricsRegistry struct {
+	service string
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[routeKey]*histogram
+}
+
+func newMetricsRegistry(service string) *metricsRegistry {
+	return &metricsRegistry{
+		service:   service,
+		requests:  map[requestKey]uint64{},
+		latencies: map[routeKey]*histogram{},
+	}
+}
+
+func (m *metricsRegistry) observe(method, path, status string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{method: method, path: path, status: status}]++
+
+	rk := routeKey{method: method, path: path}
+	hist, ok := m.latencies[rk]
+	if !ok {
+		hist = newHistogram(defaultLatencyBuckets)
+		m.latencies[rk] = hist
+	}
+	hist.observe(duration.Seconds())
+}
+
+// render writes every tracked metric plus a handful of Go runtime gauges
+// in Prometheus text exposition format.
+func (m *metricsRegistry) render(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP http_requests_total Total HTTP requests by route and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for key, count := range m.requests {
+		fmt.Fprintf(w, "http_requests_total{service=%q,method=%q,path=%q,status=%q} %d\n", m.service, key.method, key.path, key.status, count)
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds HTTP request latency by route.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for key, hist := range m.latencies {
+		for i, bound := range hist.buckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{service=%q,method=%q,path=%q,le=%q} %d\n",
+				m.service, key.method, key.path, strconv.FormatFloat(bound, 'g', -1, 64), hist.counts[i])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{service=%q,method=%q,path=%q,le=\"+Inf\"} %d\n", m.service, key.method, key.path, hist.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{service=%q,method=%q,path=%q} %g\n", m.service, key.method, key.path, hist.sum)
+		fmt.Fprintf(w, "http_request_duration_seconds_count{service=%q,method=%q,path=%q} %d\n", m.service, key.method, key.path, hist.count)
+	}
+
+	m.renderRuntimeMetrics(w)
+}
+
+func (m *metricsRegistry) renderRuntimeMetrics(w io.Writer) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	fmt.Fprintln(w, "# HELP go_goroutines Number of goroutines that currently exist.")
+	fmt.Fprintln(w, "# TYPE go_goroutines gauge")
+	fmt.Fprintf(w, "go_goroutines{service=%q} %d\n", m.service, runtime.NumGoroutine())
+
+	fmt.Fprintln(w, "# HELP go_memstats_heap_alloc_bytes Bytes of allocated heap objects.")
+	fmt.Fprintln(w, "# TYPE go_memstats_heap_alloc_bytes gauge")
+	fmt.Fprintf(w, "go_memstats_heap_alloc_bytes{service=%q} %d\n", m.service, stats.HeapAlloc)
+
+	fmt.Fprintln(w, "# HELP go_gc_pause_seconds_last Duration of the most recently completed garbage collection pause.")
+	fmt.Fprintln(w, "# TYPE go_gc_pause_seconds_last gauge")
+	lastPause := float64(0)
+	if stats.NumGC > 0 {
+		lastPause = float64(stats.PauseNs[(stats.NumGC+255)%256]) / 1e9
+	}
+	fmt.Fprintf(w, "go_gc_pause_seconds_last{service=%q} %g\n", m.service, lastPause)
+
+	fmt.Fprintln(w, "# HELP process_uptime_seconds Seconds since the process started.")
+	fmt.Fprintln(w, "# TYPE process_uptime_seconds gauge")
+	fmt.Fprintf(w, "process_uptime_seconds{service=%q} %g\n", m.service, time.Since(startTime).Seconds())
+}
+
+// metricsMiddleware wraps a handler so every request it serves is recorded
+// into registry under the given route path: method, status code (captured
+// via statusWriter), and latency.
+func metricsMiddleware(registry *metricsRegistry, path string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next(sw, r)
+		registry.observe(r.Method, path, strconv.Itoa(sw.statusCode), time.Since(start))
+	}
+}
+
+func metricsHandler(registry *metricsRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		registry.render(w)
+	}
+}
+
+// envDuration reads name as a count of seconds, falling back to def if the
+// variable is unset or not a positive integer.
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs <= 0 {
+		return def
+	}
+	return time.Duration(secs) * time.Second
+}
+
 func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "80"
@@ -36,115 +238,80 @@ func main() {
 		serviceName = "unknown"
 	}
 
-	http.HandleFunc("/", homeHandler(serviceName))
-	http.HandleFunc("/health", healthHandler(serviceName))
-	http.HandleFunc("/api/status", statusHandler(serviceName))
+	registry := newMetricsRegistry(serviceName)
 
-	log.Printf("🚀 %s service listening on port %s", serviceName, port)
-	log.Printf("📊 Health check: http://localhost:%s/health", port)
-	
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal("Server failed to start:", err)
+	checks := newHealthRegistry()
+	registerChecksFromEnv(checks)
+	checkInterval := envDuration("HEALTH_CHECK_INTERVAL_SECONDS", 15*time.Second)
+	go checks.Run(context.Background(), checkInterval)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", metricsMiddleware(registry, "/", homeHandler(serviceName)))
+	mux.HandleFunc("/livez", metricsMiddleware(registry, "/livez", livezHandler()))
+	mux.HandleFunc("/readyz", metricsMiddleware(registry, "/readyz", readyzHandler(checks)))
+	mux.HandleFunc("/health", metricsMiddleware(registry, "/health", healthHandler(serviceName, checks)))
+	mux.HandleFunc("/api/status", metricsMiddleware(registry, "/api/status", statusHandler(serviceName)))
+	mux.HandleFunc("/metrics", metricsHandler(registry))
+	mux.HandleFunc("/static/", metricsMiddleware(registry, "/static/", assetsHandler()))
+
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      chainMiddleware(mux),
+		ReadTimeout:  envDuration("READ_TIMEOUT_SECONDS", 5*time.Second),
+		WriteTimeout: envDuration("WRITE_TIMEOUT_SECONDS", 10*time.Second),
+		IdleTimeout:  envDuration("IDLE_TIMEOUT_SECONDS", 120*time.Second),
 	}
-}
+	shutdownTimeout := envDuration("SHUTDOWN_TIMEOUT_SECONDS", 15*time.Second)
 
-func homeHandler(serviceName string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
-			return
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.Info("service listening",
+			slog.String("service", serviceName),
+			slog.String("port", port),
+		)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("server failed to start", slog.String("error", err.Error()))
+			os.Exit(1)
 		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	logger.Info("shutdown signal received, draining in-flight requests", slog.Duration("timeout", shutdownTimeout))
 
-		html := fmt.Sprintf(`
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>%s Service</title>
-    <style>
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            max-width: 800px;
-            margin: 0 auto;
-            padding: 2rem;
-            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
-            min-height: 100vh;
-            color: white;
-        }
-        .container {
-            text-align: center;
-            background: rgba(255, 255, 255, 0.1);
-            backdrop-filter: blur(10px);
-            border-radius: 15px;
-            padding: 2rem;
-            border: 1px solid rgba(255, 255, 255, 0.2);
-        }
-        h1 { margin-bottom: 1rem; }
-        .info { margin: 1rem 0; padding: 1rem; background: rgba(255, 255, 255, 0.1); border-radius: 8px; }
-        .links a {
-            display: inline-block;
-            margin: 0.5rem;
-            padding: 0.5rem 1rem;
-            background: rgba(255, 255, 255, 0.2);
-            color: white;
-            text-decoration: none;
-            border-radius: 5px;
-            border: 1px solid rgba(255, 255, 255, 0.3);
-        }
-        .links a:hover { background: rgba(255, 255, 255, 0.3); }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>🚀 %s Service</h1>
-        <div class="info">
-            <p><strong>Status:</strong> Running</p>
-            <p><strong>Language:</strong> Go</p>
-            <p><strong>Timestamp:</strong> %s</p>
-        </div>
-        <div class="links">
-            <a href="/health">Health Check</a>
-            <a href="/api/status">API Status</a>
-        </div>
-    </div>
-</body>
-</html>`, serviceName, serviceName, time.Now().Format(time.RFC3339))
-
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(html))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown failed", slog.String("error", err.Error()))
 	}
 }
 
-func healthHandler(serviceName string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		uptime := time.Since(startTime)
-		
-		response := HealthResponse{
-			Status:    "healthy",
-			Service:   serviceName,
-			Timestamp: time.Now(),
-			Uptime:    uptime.String(),
-		}
+// homeTemplate renders HomeResponse as the service's landing page, parsed
+// from the embedded web/templates/home.html rather than a Go string
+// literal so a downstream deployment can override it without touching this
+// file.
+var homeTemplate = template.Must(template.ParseFS(templateFS, "web/templates/home.html"))
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
-	}
+func homeHandler(serviceName string) http.HandlerFunc {
+	type emptyRequest struct{}
+	return Wrap(func(r *http.Request, _ *emptyRequest) (*HomeResponse, error) {
+		if r.URL.Path != "/" {
+			return nil, &APIError{Status: http.StatusNotFound, Message: "not found"}
+		}
+		return &HomeResponse{Service: serviceName, Timestamp: time.Now(), BuildVersion: buildVersion}, nil
+	}, JSONRenderer[HomeResponse]("ok"), TemplateRenderer[HomeResponse](homeTemplate))
 }
 
 func statusHandler(serviceName string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		response := Response{
+	type emptyRequest struct{}
+	return Wrap(func(r *http.Request, _ *emptyRequest) (*Response, error) {
+		return &Response{
 			Service:   serviceName,
 			Message:   fmt.Sprintf("Hello from %s", serviceName),
 			Timestamp: time.Now(),
 			Status:    "running",
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
-	}
+		}, nil
+	}, JSONRenderer[Response]("ok"), nil)
 }
\ No newline at end of file